@@ -0,0 +1,124 @@
+// This file defines a reader for access logs in the Common/Combined Log Format or JSONL
+// (used to replay recorded traffic with its original timing, see [Replay])
+
+package hit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogEntry is a single request of an access log.
+// (a JSONL log may contain other fields, e.g. the response metadata recorded by cmd/hitrecord)
+type LogEntry struct {
+	Time   time.Time   `json:"time"`             // Time the request arrived at the server
+	Method string      `json:"method"`           // Method of the request (Default: GET)
+	Path   string      `json:"path"`             // Path (and query) of the request
+	Header http.Header `json:"header,omitempty"` // Header of the request (JSONL only)
+	Body   string      `json:"body,omitempty"`   // Body of the request (JSONL only)
+
+	// RawBody is the body of the request encoded in base64 (JSONL only)
+	// It's used instead of Body if set, e.g. for a binary body (a JSON string would corrupt its invalid UTF-8)
+	RawBody []byte `json:"raw_body,omitempty"`
+}
+
+// LogFormat is the format of an access log.
+type LogFormat int
+
+const (
+	LogCommon LogFormat = iota // Common or Combined Log Format (e.g. apache and nginx)
+	LogJSONL                   // one JSON [LogEntry] per line
+)
+
+// clfLine matches a line of the Common Log Format
+// (the Combined Log Format only adds fields at the end of the line), e.g.
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+var clfLine = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "([^"]*)"`)
+
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// ReadAccessLog returns an iterator over the entries of an access log read from r.
+// Lines without a request (e.g. `"-"` for a closed connection) are skipped.
+// The iterator stops after yielding an error for a malformed line.
+func ReadAccessLog(r io.Reader, format LogFormat) iter.Seq2[LogEntry, error] {
+	return func(yield func(LogEntry, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // allow long lines (e.g. recorded bodies)
+
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+
+			var (
+				e   LogEntry
+				ok  bool
+				err error
+			)
+			switch format {
+			case LogJSONL:
+				e, ok, err = parseJSONLEntry(text)
+			default:
+				e, ok, err = parseCLFEntry(text)
+			}
+
+			if err != nil {
+				yield(LogEntry{}, fmt.Errorf("access log line %d: %w", line, err))
+				return
+			}
+			if !ok {
+				continue
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			yield(LogEntry{}, fmt.Errorf("reading access log: %w", err))
+		}
+	}
+}
+
+func parseCLFEntry(line string) (LogEntry, bool, error) {
+	m := clfLine.FindStringSubmatch(line)
+	if m == nil {
+		return LogEntry{}, false, fmt.Errorf("not in the common log format: %q", line)
+	}
+
+	t, err := time.Parse(clfTime, m[1])
+	if err != nil {
+		return LogEntry{}, false, fmt.Errorf("invalid time: %w", err)
+	}
+
+	// the request line, e.g. "GET /items?page=2 HTTP/1.1"
+	parts := strings.Fields(m[2])
+	if len(parts) < 2 {
+		return LogEntry{}, false, nil // no request (e.g. "-")
+	}
+
+	return LogEntry{Time: t, Method: parts[0], Path: parts[1]}, true, nil
+}
+
+func parseJSONLEntry(line string) (LogEntry, bool, error) {
+	var e LogEntry
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		return LogEntry{}, false, err
+	}
+	if e.Path == "" {
+		return LogEntry{}, false, nil
+	}
+	if e.Method == "" {
+		e.Method = http.MethodGet
+	}
+	return e, true, nil
+}
@@ -0,0 +1,309 @@
+// This file defines the authentication providers of a run (see [Options])
+// a provider adds the credentials to each request right before it's sent (e.g. a bearer token
+// that's refreshed in the background and shared by all the dispatch workers)
+
+package hit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Authenticator adds the credentials to a request (see [Options]).
+// It's called concurrently by the dispatch workers, hence it must be safe for concurrent use.
+// (see [BearerToken] and [BasicCredentials] for static credentials)
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthFunc is a function that implements [Authenticator].
+type AuthFunc func(req *http.Request) error
+
+func (f AuthFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// BearerToken returns an [Authenticator] that sets a static bearer token in the Authorization header
+// (i.e. the [BearerAuth] middleware as the authentication of the run).
+func BearerToken(token string) Authenticator {
+	return staticAuth(bearerAuthorization(token))
+}
+
+// BasicCredentials returns an [Authenticator] that sets static basic authentication credentials
+// (i.e. the [BasicAuth] middleware as the authentication of the run).
+func BasicCredentials(username, password string) Authenticator {
+	return staticAuth(basicAuthorization(username, password))
+}
+
+// staticAuth sets the given Authorization header value on each request.
+func staticAuth(authorization string) AuthFunc {
+	return func(req *http.Request) error {
+		req.Header.Set("Authorization", authorization)
+		return nil
+	}
+}
+
+// bearerAuthorization returns the Authorization header value of a bearer token.
+func bearerAuthorization(token string) string {
+	return "Bearer " + token
+}
+
+// basicAuthorization returns the Authorization header value of basic authentication credentials.
+func basicAuthorization(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// AuthError is the error of a request that couldn't be authenticated (hence, wasn't sent).
+// The auth errors are counted separately from the errors of the targets (see [Summary]).
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return "authentication failed: " + e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// isAuthError reports whether err is (or wraps) an [AuthError].
+// (the target of errors.As escapes to the heap, hence, it's only declared for an actual error
+// so that the results without an error don't allocate)
+func isAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
+
+// ClientCredentials is an [Authenticator] that gets a bearer token from an OAuth2 token endpoint
+// with the client credentials flow (RFC 6749, section 4.4).
+//
+// The token is fetched with the first request and shared by all the workers.
+// It's refreshed in the background before it expires (so that the requests never wait for it),
+// and fetched again by the next request if the refresh fails until it expires.
+type ClientCredentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	client       *http.Client
+
+	token atomic.Pointer[oauthToken] // current token (nil until the first fetch)
+
+	mu     sync.Mutex  // serializes the fetches (so that the workers don't fetch the same token)
+	timer  *time.Timer // background refresh (nil if not scheduled)
+	closed bool
+}
+
+type oauthToken struct {
+	value  string
+	expiry time.Time // zero if the token doesn't expire
+}
+
+// refreshMargin is how long before its expiry a token is refreshed (at most a fifth of its lifetime)
+const refreshMargin = 30 * time.Second
+
+// NewClientCredentials returns a [ClientCredentials] authenticator for the token endpoint.
+// The client (nil for a default one) is used to fetch the tokens.
+func NewClientCredentials(tokenURL, clientID, clientSecret string, scopes []string, client *http.Client) *ClientCredentials {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &ClientCredentials{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+		client:       client,
+	}
+}
+
+// Authenticate sets the current token (fetching it if there's no valid token yet).
+func (c *ClientCredentials) Authenticate(req *http.Request) error {
+	t := c.token.Load()
+	if !t.valid() {
+		var err error
+		if t, err = c.fetch(req.Context(), t); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+t.value)
+	return nil
+}
+
+// Close stops the background refresh.
+func (c *ClientCredentials) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	return nil
+}
+
+func (t *oauthToken) valid() bool {
+	return t != nil && (t.expiry.IsZero() || time.Now().Before(t.expiry))
+}
+
+// fetch gets a new token unless another worker has replaced the old one in the meantime.
+func (c *ClientCredentials) fetch(ctx context.Context, old *oauthToken) (*oauthToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t := c.token.Load(); t != old && t.valid() {
+		return t, nil // fetched by another worker
+	}
+
+	t, err := c.request(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.token.Store(t)
+	c.schedule(t)
+	return t, nil
+}
+
+// schedule refreshes the token in the background before it expires (c.mu must be held).
+func (c *ClientCredentials) schedule(t *oauthToken) {
+	if c.closed || t.expiry.IsZero() {
+		return
+	}
+
+	lifetime := time.Until(t.expiry)
+	refreshIn := lifetime - min(refreshMargin, lifetime/5)
+
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timer = time.AfterFunc(refreshIn, func() {
+		// a failed refresh is retried by the first request after the token expires
+		_, _ = c.fetch(context.Background(), t)
+	})
+}
+
+// request gets a token from the token endpoint.
+func (c *ClientCredentials) request(ctx context.Context) (*oauthToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.scopes) > 0 {
+		form.Set("scope", strings.Join(c.scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret)) // (RFC 6749, section 2.3.1)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting a token: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading the token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s: %s", res.Status, bytes.TrimSpace(body))
+	}
+
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"` // in seconds (0 if it doesn't expire)
+	}
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("parsing the token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+
+	t := &oauthToken{value: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		t.expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// HMACSigner is an [Authenticator] that signs each request with a shared secret (HMAC-SHA256).
+//
+// The signature covers the method, the path and query, the X-Date header (set to the current time)
+// and the SHA-256 of the body, one per line:
+//
+//	POST
+//	/items?page=2
+//	Mon, 02 Jan 2006 15:04:05 GMT
+//	<hex sha256 of the body>
+//
+// It's sent as "Authorization: HMAC-SHA256 KeyId=<key id>, Signature=<base64 signature>".
+type HMACSigner struct {
+	KeyID  string
+	Secret []byte
+}
+
+// Authenticate signs the request.
+func (s HMACSigner) Authenticate(req *http.Request) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return fmt.Errorf("reading the body to sign: %w", err)
+	}
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	req.Header.Set("X-Date", date)
+
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC-SHA256 KeyId=%s, Signature=%s", s.KeyID, s.Sign(req.Method, req.URL.RequestURI(), date, body)))
+	return nil
+}
+
+// Sign returns the (base64) signature of a request (e.g. to verify it on the server).
+func (s HMACSigner) Sign(method, requestURI, date string, body []byte) string {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, s.Secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", method, requestURI, date, hex.EncodeToString(sum[:]))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// requestBody returns a copy of the body of a request (without consuming it).
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		// read it and put it back
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return body, nil
+	}
+
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,155 @@
+package hit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer returns a token server that issues tokens ("token-1", "token-2", ...)
+// expiring after expiresIn seconds to the client "client" with the secret "secret".
+func newTokenServer(expiresIn int, fetches *atomic.Int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id, secret, _ := req.BasicAuth()
+		if req.FormValue("grant_type") != "client_credentials" || id != "client" || secret != "secret" {
+			http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		n := fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": %d}`, n, expiresIn)
+	}))
+}
+
+// test that the token is fetched once for all the workers and refreshed in the background before it expires
+func TestClientCredentials(t *testing.T) {
+
+	var fetches atomic.Int64
+	server := newTokenServer(2, &fetches)
+	defer server.Close()
+
+	auth := NewClientCredentials(server.URL, "client", "secret", []string{"read"}, nil)
+	defer auth.Close()
+
+	// concurrent workers share the same token
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			req := getTestHttpRequest()
+			if err := auth.Authenticate(req); err != nil {
+				t.Errorf("Authenticate() = %v; want no error\n", err)
+			}
+			if got := req.Header.Get("Authorization"); got != "Bearer token-1" {
+				t.Errorf("Authorization: got = %q, want = %q\n", got, "Bearer token-1")
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("token fetches: got = %d, want = %d\n", n, 1)
+	}
+
+	// the token (expiring after 2s) is refreshed in the background after 1.6s
+	// (polled until it's refreshed, the next refresh is 1.6s later)
+	deadline := time.Now().Add(5 * time.Second)
+	for fetches.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("token fetches: got = %d, want = %d\n", n, 2)
+	}
+
+	req := getTestHttpRequest()
+	if err := auth.Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() = %v; want no error\n", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer token-2" {
+		t.Errorf("Authorization: got = %q, want = %q\n", got, "Bearer token-2")
+	}
+}
+
+// test that the requests that can't be authenticated aren't sent and are reported as auth errors
+func TestAuthErrors(t *testing.T) {
+	const N int = 5
+
+	var fetches atomic.Int64
+	server := newTokenServer(60, &fetches)
+	defer server.Close()
+
+	auth := NewClientCredentials(server.URL, "client", "wrong secret", nil, nil)
+	defer auth.Close()
+
+	var sent atomic.Int64
+	opts := Options{Auth: auth}
+	opts.Send = func(_ *http.Request) Result {
+		sent.Add(1)
+		return Result{Status: http.StatusOK}
+	}
+
+	results, err := SendN(context.Background(), N, opts, getTestHttpRequest())
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+	s := Summarize(results)
+
+	if s.AuthErrors != N || s.Requests != 0 || s.Errors != 0 {
+		t.Errorf("AuthErrors, Requests, Errors: got = %d, %d, %d, want = %d, 0, 0\n", s.AuthErrors, s.Requests, s.Errors, N)
+	}
+	if sent.Load() != 0 {
+		t.Errorf("sent requests: got = %d, want = %d\n", sent.Load(), 0)
+	}
+}
+
+func TestStaticAuth(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		auth Authenticator
+		want string
+	}{
+		{name: "bearer", auth: BearerToken("token"), want: "Bearer token"},
+		{name: "basic", auth: BasicCredentials("user", "pass"), want: "Basic dXNlcjpwYXNz"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := getTestHttpRequest()
+			if err := tt.auth.Authenticate(req); err != nil {
+				t.Fatalf("Authenticate() = %v; want no error\n", err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization: got = %q, want = %q\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+
+	signer := HMACSigner{KeyID: "key-1", Secret: []byte("secret")}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8082/items?page=2", strings.NewReader(`{"id": 1}`))
+	if err := signer.Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() = %v; want no error\n", err)
+	}
+
+	// the server verifies the signature with the same secret
+	date := req.Header.Get("X-Date")
+	want := "HMAC-SHA256 KeyId=key-1, Signature=" + signer.Sign(http.MethodPost, "/items?page=2", date, []byte(`{"id": 1}`))
+	if got := req.Header.Get("Authorization"); date == "" || got != want {
+		t.Errorf("Authorization: got = %q (X-Date %q), want = %q\n", got, date, want)
+	}
+
+	// the body is still sent
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"id": 1}` {
+		t.Errorf("body: got = %q, want = %q\n", body, `{"id": 1}`)
+	}
+}
@@ -0,0 +1,59 @@
+package hit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// Benchmark is the result of a [SelfBenchmark].
+type Benchmark struct {
+	Requests         int           // Requests is the number of (no-op) requests
+	Duration         time.Duration // Duration is the clock time of the run
+	Rate             float64       // Rate is the maximum request rate of the client (requests per second)
+	AllocsPerRequest float64       // AllocsPerRequest is the number of heap allocations per request
+	BytesPerRequest  float64       // BytesPerRequest is the number of bytes allocated per request
+}
+
+// SelfBenchmark measures the overhead of the client itself (i.e. the maximum rate it can send requests at).
+// It runs n requests through the pipeline with a no-op Send function (nothing is sent over the network)
+// and summarizes their results as a real run does. The other options are used as given
+// (e.g. to compare the rate at different concurrency levels).
+func SelfBenchmark(ctx context.Context, n int, opts Options) (Benchmark, error) {
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost/", http.NoBody)
+	if err != nil {
+		return Benchmark{}, err
+	}
+
+	opts.Send = func(_ *http.Request) Result {
+		return Result{Status: http.StatusOK}
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	results, err := SendN(ctx, n, opts, req)
+	if err != nil {
+		return Benchmark{}, err
+	}
+	s := Summarize(results)
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	if s.Requests == 0 {
+		return Benchmark{}, fmt.Errorf("no request completed: %w", context.Cause(ctx))
+	}
+
+	return Benchmark{
+		Requests:         s.Requests,
+		Duration:         elapsed,
+		Rate:             float64(s.Requests) / elapsed.Seconds(),
+		AllocsPerRequest: float64(after.Mallocs-before.Mallocs) / float64(s.Requests),
+		BytesPerRequest:  float64(after.TotalAlloc-before.TotalAlloc) / float64(s.Requests),
+	}, nil
+}
@@ -0,0 +1,22 @@
+package hit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSelfBenchmark(t *testing.T) {
+	const N int = 1000
+
+	b, err := SelfBenchmark(context.Background(), N, Options{Concurrency: 4})
+	if err != nil {
+		t.Fatalf("SelfBenchmark() = %v; want no error\n", err)
+	}
+
+	if b.Requests != N {
+		t.Errorf("Requests: got = %d, want = %d\n", b.Requests, N)
+	}
+	if b.Rate <= 0 || b.AllocsPerRequest <= 0 {
+		t.Errorf("got = %+v, want a positive rate and allocations\n", b)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// authSpec implements flag's Value interface to parse the authentication of the requests in the form:
+//
+//	bearer:TOKEN
+//	basic:USER:PASSWORD
+//	oauth2:CLIENT_ID:CLIENT_SECRET@TOKEN_URL   (client credentials flow, the token is refreshed in the background)
+//	hmac:KEY_ID:SECRET                         (HMAC-SHA256 request signing)
+//
+// A secret (i.e. TOKEN, PASSWORD, CLIENT_SECRET or SECRET) can be read from an environment variable
+// (env:NAME) or a file (file:PATH) to keep it out of the command line (e.g. out of ps and the shell history).
+type authSpec struct {
+	scheme string
+	auth   hit.Authenticator
+}
+
+func (a *authSpec) String() string {
+	return a.scheme
+}
+
+func (a *authSpec) Set(s string) error {
+	if a.scheme != "" {
+		return errors.New("the authentication is already set (the flag can only be given once)")
+	}
+	scheme, rest, _ := strings.Cut(s, ":")
+
+	switch scheme {
+	case "bearer":
+		if rest == "" {
+			return errors.New("want bearer:TOKEN")
+		}
+		token, err := readSecret(rest)
+		if err != nil {
+			return err
+		}
+		a.auth = hit.BearerToken(token)
+	case "basic":
+		user, password, ok := strings.Cut(rest, ":")
+		if !ok || user == "" {
+			return errors.New("want basic:USER:PASSWORD")
+		}
+		password, err := readSecret(password)
+		if err != nil {
+			return err
+		}
+		a.auth = hit.BasicCredentials(user, password)
+	case "oauth2":
+		// the secret may contain "@" (the token url comes after the last one)
+		i := strings.LastIndex(rest, "@")
+		if i < 0 {
+			return errors.New("want oauth2:CLIENT_ID:CLIENT_SECRET@TOKEN_URL")
+		}
+		id, secret, ok := strings.Cut(rest[:i], ":")
+		u, err := url.Parse(rest[i+1:])
+		if !ok || id == "" || err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("want oauth2:CLIENT_ID:CLIENT_SECRET@TOKEN_URL with a valid token url")
+		}
+		if secret, err = readSecret(secret); err != nil {
+			return err
+		}
+		a.auth = hit.NewClientCredentials(u.String(), id, secret, nil, nil)
+	case "hmac":
+		id, secret, ok := strings.Cut(rest, ":")
+		if !ok || id == "" || secret == "" {
+			return errors.New("want hmac:KEY_ID:SECRET")
+		}
+		secret, err := readSecret(secret)
+		if err != nil {
+			return err
+		}
+		a.auth = hit.HMACSigner{KeyID: id, Secret: []byte(secret)}
+	default:
+		return fmt.Errorf("unknown scheme %q: want bearer, basic, oauth2 or hmac", scheme)
+	}
+
+	a.scheme = scheme
+	return nil
+}
+
+// readSecret returns a secret given as is, or read from an environment variable (env:NAME)
+// or a file (file:PATH, without its trailing newline).
+func readSecret(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "env:"):
+		name := strings.TrimPrefix(s, "env:")
+		v := os.Getenv(name)
+		if v == "" {
+			return "", fmt.Errorf("environment variable %s of the secret is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "file:"):
+		b, err := os.ReadFile(strings.TrimPrefix(s, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("error while reading the secret: %w", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	return s, nil
+}
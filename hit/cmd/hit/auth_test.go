@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+func TestAuthSpecSet(t *testing.T) {
+
+	// a secret read from a file (without its trailing newline)
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HIT_TEST_SECRET", "from-env")
+
+	testCases := []struct {
+		name  string
+		value string
+		want  string // the Authorization header set ("" if it's not static)
+	}{
+		{name: "bearer", value: "bearer:token", want: "Bearer token"},
+		{name: "bearer_env", value: "bearer:env:HIT_TEST_SECRET", want: "Bearer from-env"},
+		{name: "bearer_file", value: "bearer:file:" + secretFile, want: "Bearer from-file"},
+		{name: "basic", value: "basic:user:pass", want: "Basic dXNlcjpwYXNz"},
+		{name: "basic_password_with_colon", value: "basic:user:pa:ss", want: "Basic dXNlcjpwYTpzcw=="},
+		{name: "oauth2", value: "oauth2:id:s@cret@https://auth.example.com/token"},
+		{name: "hmac", value: "hmac:key-1:env:HIT_TEST_SECRET"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var a authSpec
+			if err := a.Set(tt.value); err != nil {
+				t.Fatalf("Set(%q) = %v; want no error\n", tt.value, err)
+			}
+			if a.auth == nil {
+				t.Fatalf("Set(%q): got no authenticator, want one\n", tt.value)
+			}
+			if c, ok := a.auth.(*hit.ClientCredentials); ok {
+				defer c.Close() // (nothing is fetched before the first request)
+			}
+			if tt.want == "" {
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:8082", nil)
+			if err := a.auth.Authenticate(req); err != nil {
+				t.Fatalf("Authenticate() = %v; want no error\n", err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization: got = %q, want = %q\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthSpecSetInvalid(t *testing.T) {
+
+	testCases := []struct {
+		value string
+		want  string
+	}{
+		{value: "bearer:", want: "want bearer:TOKEN"},
+		{value: "basic:user", want: "want basic:USER:PASSWORD"},
+		{value: "oauth2:id:secret", want: "want oauth2:CLIENT_ID:CLIENT_SECRET@TOKEN_URL"},
+		{value: "oauth2:id:secret@localhost", want: "with a valid token url"},
+		{value: "hmac:key-1", want: "want hmac:KEY_ID:SECRET"},
+		{value: "digest:user:pass", want: `unknown scheme "digest"`},
+		{value: "bearer:env:HIT_TEST_UNSET", want: "environment variable HIT_TEST_UNSET of the secret is not set"},
+		{value: "bearer:file:" + filepath.Join(t.TempDir(), "missing"), want: "error while reading the secret"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.value, func(t *testing.T) {
+			var a authSpec
+			if err := a.Set(tt.value); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Set(%q) error = %v; want an error containing %q\n", tt.value, err, tt.want)
+			}
+		})
+	}
+
+	// the flag can only be given once
+	var a authSpec
+	if err := a.Set("bearer:token"); err != nil {
+		t.Fatalf("Set() = %v; want no error\n", err)
+	}
+	if err := a.Set("basic:user:pass"); err == nil {
+		t.Errorf("second Set() error = <nil>; want an error\n")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// runCompare runs the compare sub command that compares a new run with a base run from their run files
+// and exits with an error if the new run is significantly worse (e.g. to fail a deploy pipeline):
+//
+//	hit compare [-alpha 0.05] [-min-delta 5%] base.jsonl new.jsonl
+func runCompare(args []string, stdout, stderr io.Writer) error {
+
+	flagSet := flag.NewFlagSet("hit compare", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: %s [options] base-run-file new-run-file\noptions:\n", flagSet.Name())
+		flagSet.PrintDefaults()
+	}
+
+	var opts hit.CompareOptions
+	flagSet.Float64Var(&opts.Alpha, "alpha", 0.05, "significance `level` of the tests (a difference is significant if its p-value is lower)")
+	flagSet.Func("min-delta", "minimum increase of the latency (median or percentile) counted as a regression, e.g. 5% (default 0%, any significant increase)", func(s string) error {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || v < 0 {
+			return errors.New("want a positive percentage (e.g. 5%)")
+		}
+		opts.MinDelta = v / 100
+		return nil
+	})
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if flagSet.NArg() != 2 {
+		flagSet.Usage()
+		return errors.New("want a base and a new run file")
+	}
+
+	baseInfo, base, err := loadRun(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+	newInfo, candidate, err := loadRun(flagSet.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Comparing:\n    base: %s (%s, %s)\n    new:  %s (%s, %s)\n",
+		flagSet.Arg(0), baseInfo.Target, baseInfo.Start.Format(time.RFC3339),
+		flagSet.Arg(1), newInfo.Target, newInfo.Start.Format(time.RFC3339),
+	)
+
+	comparisons := hit.Compare(base, candidate, opts)
+	printComparisons(comparisons, opts, stdout)
+
+	var regressions []string
+	for _, c := range comparisons {
+		if c.Regression() {
+			regressions = append(regressions, comparisonName(c))
+		}
+	}
+	if len(regressions) > 0 {
+		return fmt.Errorf("significant regression of %s", strings.Join(regressions, ", "))
+	}
+	return nil
+}
+
+// loadRun reads the metadata and the results of a run file
+func loadRun(name string) (hit.RunInfo, []hit.Result, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return hit.RunInfo{}, nil, fmt.Errorf("error while opening the run file: %w", err)
+	}
+	defer f.Close()
+
+	info, results, err := hit.LoadRun(f)
+	if err != nil {
+		return hit.RunInfo{}, nil, fmt.Errorf("error while reading the run file %q: %w", name, err)
+	}
+	return info, results, nil
+}
+
+// printComparisons prints the latency and the error rate of each comparison (benchstat style)
+// with the significant differences flagged
+func printComparisons(comparisons []hit.Comparison, opts hit.CompareOptions, stdout io.Writer) {
+	fmt.Fprintf(stdout, "\nMedian latency (successful requests, 95%% confidence interval):\n")
+	fmt.Fprintf(stdout, "    %-16s %-28s %-28s %-9s %s\n", "Target", "Base", "New", "Delta", "")
+	for _, c := range comparisons {
+		fmt.Fprintf(stdout, "    %-16s %-28s %-28s %-9s %s\n",
+			comparisonName(c),
+			medianInterval(c.Base),
+			medianInterval(c.New),
+			delta(fmt.Sprintf("%+.1f%%", c.MedianDelta*100), c.P, opts.Alpha),
+			significance(c.P, c.Base.Requests, c.New.Requests),
+		)
+	}
+
+	for _, c := range comparisons {
+		if len(c.Percentiles) == 0 {
+			continue
+		}
+		fmt.Fprintf(stdout, "\nPercentiles (%s):\n", comparisonName(c))
+		for _, p := range c.Percentiles {
+			higher := ""
+			if p.Higher {
+				higher = "  (significantly higher)"
+			}
+			fmt.Fprintf(stdout, "    p%-6g %-12s %-12s %+.1f%%%s\n", p.P, p.Base.Round(time.Microsecond), p.New.Round(time.Microsecond), p.Delta*100, higher)
+		}
+	}
+
+	fmt.Fprintf(stdout, "\nError rate:\n")
+	for _, c := range comparisons {
+		fmt.Fprintf(stdout, "    %-16s %-28s %-28s %-9s %s\n",
+			comparisonName(c),
+			fmt.Sprintf("%.2f%% (%d/%d)", c.Base.ErrorRate*100, c.Base.Errors, c.Base.Requests),
+			fmt.Sprintf("%.2f%% (%d/%d)", c.New.ErrorRate*100, c.New.Errors, c.New.Requests),
+			delta(fmt.Sprintf("%+.2fpp", (c.New.ErrorRate-c.Base.ErrorRate)*100), c.ErrorP, opts.Alpha), // (percentage points)
+			fmt.Sprintf("p=%.3f", c.ErrorP),
+		)
+	}
+
+	fmt.Fprintf(stdout, "\n(~ means the difference is not significant at alpha=%g", opts.Alpha)
+	if len(comparisons) > 1 {
+		fmt.Fprintf(stdout, ", the p-values are adjusted for the %d comparisons", len(comparisons))
+	}
+	if opts.MinDelta > 0 {
+		fmt.Fprintf(stdout, ", an increase of the latency below %g%% isn't a regression", opts.MinDelta*100)
+	}
+	fmt.Fprintf(stdout, ")\n")
+
+	for _, c := range comparisons {
+		switch {
+		case c.Slower:
+			fmt.Fprintf(stdout, "\nRegression: %s is slower (%s)\n", comparisonName(c), slowerPercentiles(c))
+		case c.Faster:
+			fmt.Fprintf(stdout, "\nImprovement: %s is faster (median %+.1f%%)\n", comparisonName(c), c.MedianDelta*100)
+		}
+		if c.MoreError {
+			fmt.Fprintf(stdout, "\nRegression: %s fails more (error rate %.2f%% to %.2f%%)\n", comparisonName(c), c.Base.ErrorRate*100, c.New.ErrorRate*100)
+		}
+	}
+}
+
+// slowerPercentiles describes how a comparison is slower, e.g. "median +12.0%, p99 +85.3%"
+// (the median and the percentiles that are significantly higher)
+func slowerPercentiles(c hit.Comparison) string {
+	changes := []string{fmt.Sprintf("median %+.1f%%", c.MedianDelta*100)}
+	for _, p := range c.Percentiles {
+		if p.Higher && p.P != 50 {
+			changes = append(changes, fmt.Sprintf("p%g %+.1f%%", p.P, p.Delta*100))
+		}
+	}
+	return strings.Join(changes, ", ")
+}
+
+// comparisonName returns the name of the target of a comparison ("all" for all the targets)
+func comparisonName(c hit.Comparison) string {
+	if c.Target == "" {
+		return "all"
+	}
+	return c.Target
+}
+
+// medianInterval formats a median with its confidence interval, e.g. "12.1ms (11.9ms..12.4ms)"
+func medianInterval(s hit.Sample) string {
+	if s.Median == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s..%s)", s.Median.Round(time.Microsecond), s.MedianLow.Round(time.Microsecond), s.MedianHigh.Round(time.Microsecond))
+}
+
+// delta returns a formatted change or "~" if it's not significant (i.e. likely noise)
+func delta(change string, p, alpha float64) string {
+	if p >= alpha {
+		return "~"
+	}
+	return change
+}
+
+// significance formats the p-value of a test and the sizes of its samples, e.g. "p=0.001 n=1000+1000"
+func significance(p float64, n1, n2 int) string {
+	return fmt.Sprintf("p=%.3f n=%d+%d", p, n1, n2)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+
+	base := writeRunFile(t, "base.jsonl", testResults(200, 10*time.Millisecond))
+
+	testCases := []struct {
+		name    string
+		new     string // the new run file
+		args    []string
+		want    string // printed on stdout
+		wantErr string // "" if no regression
+	}{
+		{name: "same", new: writeRunFile(t, "same.jsonl", testResults(200, 10*time.Millisecond)), want: "Median latency"},
+		{name: "slower", new: writeRunFile(t, "slower.jsonl", testResults(200, 20*time.Millisecond)), want: "Regression: all is slower", wantErr: "significant regression of all"},
+		{name: "faster", new: writeRunFile(t, "faster.jsonl", testResults(200, 5*time.Millisecond)), want: "Improvement: all is faster"},
+		{name: "slower_below_min_delta", new: writeRunFile(t, "slower.jsonl", testResults(200, 11*time.Millisecond)), args: []string{"-min-delta", "50%"}, want: "an increase of the latency below 50% isn't a regression"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			err := runCompare(append(tt.args, base, tt.new), &stdout, &stderr)
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("runCompare() = %v; want no error\n", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("runCompare() error = %v; want an error containing %q\n", err, tt.wantErr)
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("stdout: got = %q, want it to contain %q\n", stdout.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareInvalid(t *testing.T) {
+
+	base := writeRunFile(t, "base.jsonl", testResults(20, 10*time.Millisecond))
+
+	testCases := []struct {
+		name string
+		args []string
+		want string // the error
+	}{
+		{name: "single_run_file", args: []string{base}, want: "want a base and a new run file"},
+		{name: "missing_run_file", args: []string{base, filepath.Join(t.TempDir(), "missing.jsonl")}, want: "error while opening the run file"},
+		{name: "invalid_min_delta", args: []string{"-min-delta", "-5%", base, base}, want: "want a positive percentage"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			err := runCompare(tt.args, &stdout, &stderr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runCompare() error = %v; want an error containing %q\n", err, tt.want)
+			}
+		})
+	}
+}
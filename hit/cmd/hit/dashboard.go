@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// the refresh rate of the dashboard and the length of its latency sparkline
+const (
+	refreshInterval = 250 * time.Millisecond
+	sparklineWidth  = 60 // (i.e. the last 15s)
+	maxMessages     = 5  // the last messages written to stderr shown at the bottom
+)
+
+// the ansi escape sequences used to draw the dashboard
+const (
+	altScreenOn  = "\x1b[?1049h" // switch to the alternate screen (the terminal is restored on exit)
+	altScreenOff = "\x1b[?1049l"
+	hideCursor   = "\x1b[?25l"
+	showCursor   = "\x1b[?25h"
+	cursorHome   = "\x1b[H"
+	clearLine    = "\x1b[K" // clear the rest of the line
+	clearScreen  = "\x1b[J" // clear the rest of the screen
+)
+
+// dashboard is a full-screen live view of a run in the terminal
+// it's redrawn a few times per second from the summarizer of the run
+// and from the counters fed by its hooks and middleware (see runHit)
+type dashboard struct {
+	out     io.Writer
+	planned int // number of requests of the run (0 if unknown, i.e. a replay)
+	rps     int // target rate (0 if unlimited)
+	sz      *hit.Summarizer
+	pauser  *hit.Pauser
+
+	inFlight atomic.Int64 // requests sent and not yet completed
+	done     atomic.Int64 // requests completed (including the warm-up)
+
+	// latency of the successful requests completed since the last refresh
+	// (guarded together, so that a refresh doesn't see the latency of a request without its count)
+	tickMu      sync.Mutex
+	tickLatency time.Duration
+	tickCount   int
+
+	// the fields below are only used by the drawing goroutine
+	start     time.Time
+	lastDraw  time.Time
+	lastDone  int64
+	latencies []time.Duration // average latency of each refresh (0 if no requests completed)
+
+	// the last messages written to stderr while the dashboard is drawn (see messages)
+	mu     sync.Mutex
+	lines  []string
+	drawn  bool      // the dashboard is drawn (i.e. between run and stop)
+	stderr io.Writer // where the messages are written when the dashboard isn't drawn
+
+	stopOnce sync.Once
+	quit     chan struct{}
+	stopped  chan struct{}
+}
+
+func newDashboard(out io.Writer, planned, rps int, sz *hit.Summarizer, pauser *hit.Pauser) *dashboard {
+	return &dashboard{
+		out:     out,
+		planned: planned,
+		rps:     rps,
+		sz:      sz,
+		pauser:  pauser,
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// isTerminal reports whether w is a terminal (e.g. not a file or a pipe)
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// sameTerminal reports whether a and b are the same terminal (e.g. stdout and stderr of an interactive shell)
+func sameTerminal(a, b io.Writer) bool {
+	if !isTerminal(a) || !isTerminal(b) {
+		return false
+	}
+	fa, errA := a.(*os.File).Stat()
+	fb, errB := b.(*os.File).Stat()
+	return errA == nil && errB == nil && os.SameFile(fa, fb)
+}
+
+// messages returns a writer that shows the messages (e.g. "Paused") at the bottom of the dashboard
+// instead of writing them over it. They're written to stderr before and after the dashboard is drawn.
+func (d *dashboard) messages(stderr io.Writer) io.Writer {
+	d.stderr = stderr
+	return messageWriter{d}
+}
+
+type messageWriter struct{ d *dashboard }
+
+func (w messageWriter) Write(p []byte) (int, error) {
+	d := w.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.drawn {
+		return d.stderr.Write(p)
+	}
+	for line := range strings.Lines(string(p)) {
+		if line = strings.TrimSpace(line); line != "" {
+			d.lines = append(d.lines, line)
+		}
+	}
+	if len(d.lines) > maxMessages {
+		d.lines = d.lines[len(d.lines)-maxMessages:]
+	}
+	return len(p), nil
+}
+
+// middleware counts the requests in flight
+func (d *dashboard) middleware(next hit.SendFunc) hit.SendFunc {
+	return func(req *http.Request) hit.Result {
+		d.inFlight.Add(1)
+		defer d.inFlight.Add(-1)
+		return next(req)
+	}
+}
+
+// add counts a completed request (it's called by the Hooks.OnResult chain)
+func (d *dashboard) add(r hit.Result) {
+	if r.Skipped {
+		return
+	}
+	d.done.Add(1)
+	if r.Error == nil && !r.Abandoned {
+		d.tickMu.Lock()
+		d.tickLatency += r.Duration
+		d.tickCount++
+		d.tickMu.Unlock()
+	}
+}
+
+// run switches to the alternate screen and draws the dashboard until stop is called
+func (d *dashboard) run() {
+	d.start = time.Now()
+	d.lastDraw = d.start
+	fmt.Fprint(d.out, altScreenOn+hideCursor)
+
+	d.mu.Lock()
+	d.drawn = true
+	d.mu.Unlock()
+
+	go func() {
+		defer close(d.stopped)
+
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				d.draw()
+			case <-d.quit:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops drawing the dashboard and restores the terminal (so that the summary can be printed)
+// it can be called more than once (e.g. on a forced exit and at the end of the run)
+func (d *dashboard) stop() {
+	d.stopOnce.Do(func() {
+		close(d.quit)
+		<-d.stopped
+		fmt.Fprint(d.out, showCursor+altScreenOff)
+
+		d.mu.Lock()
+		d.drawn = false
+		d.mu.Unlock()
+	})
+}
+
+// draw redraws the whole dashboard in place
+func (d *dashboard) draw() {
+	now := time.Now()
+	sum := d.sz.Summary()
+
+	// current rate of the requests (since the last refresh)
+	done := d.done.Load()
+	rate := float64(done-d.lastDone) / now.Sub(d.lastDraw).Seconds()
+	d.lastDone, d.lastDraw = done, now
+
+	// average latency since the last refresh (for the sparkline)
+	var latency time.Duration
+	d.tickMu.Lock()
+	if d.tickCount > 0 {
+		latency = d.tickLatency / time.Duration(d.tickCount)
+	}
+	d.tickLatency, d.tickCount = 0, 0
+	d.tickMu.Unlock()
+	d.latencies = append(d.latencies, latency)
+	if len(d.latencies) > sparklineWidth {
+		d.latencies = d.latencies[len(d.latencies)-sparklineWidth:]
+	}
+
+	// the lines are cut to the width of the terminal (a wrapped line would scroll the dashboard)
+	width := terminalWidth(d.out)
+
+	var b strings.Builder
+	b.WriteString(cursorHome)
+	line := func(format string, args ...any) {
+		b.WriteString(truncate(fmt.Sprintf(format, args...), width))
+		b.WriteString(clearLine + "\n")
+	}
+
+	state := "running"
+	if d.pauser != nil && d.pauser.Paused() {
+		state = "PAUSED (press Enter or send SIGUSR2 to resume)"
+	}
+	line("hit - %s - %s elapsed", state, now.Sub(d.start).Round(time.Second))
+	line("")
+
+	if d.planned > 0 {
+		line("Progress:   %s", progressBar(int(done), d.planned))
+	} else {
+		line("Progress:   %d requests", done)
+	}
+
+	target := "unlimited"
+	if d.rps > 0 {
+		target = strconv.Itoa(d.rps)
+	}
+	line("RPS:        %.1f (target %s, average %.1f)", rate, target, sum.RPS)
+	line("In flight:  %d", d.inFlight.Load())
+
+	errorRate := 0.0
+	if sum.Requests > 0 {
+		errorRate = float64(sum.Errors) / float64(sum.Requests) * 100
+	}
+	line("Errors:     %d (%.1f%%)", sum.Errors, errorRate)
+	line("Latency:    p50 %s, p90 %s, p99 %s, max %s",
+		formatDuration(float64(sum.Percentile(50))),
+		formatDuration(float64(sum.Percentile(90))),
+		formatDuration(float64(sum.Percentile(99))),
+		formatDuration(float64(sum.Slowest)),
+	)
+
+	line("")
+	lo, hi := latencyRange(d.latencies)
+	line("Latency (average every %s, last %s): %s .. %s", refreshInterval, refreshInterval*sparklineWidth, formatDuration(float64(lo)), formatDuration(float64(hi)))
+	line("    %s", sparkline(d.latencies))
+
+	line("")
+	line("Statuses:")
+	total := 0
+	for _, n := range sum.Statuses {
+		total += n
+	}
+	for _, code := range slices.Sorted(maps.Keys(sum.Statuses)) {
+		name := strconv.Itoa(code)
+		if code == 0 {
+			name = "no response"
+		}
+		n := sum.Statuses[code]
+		share := float64(n) / float64(total)
+		line("    %-12s %-8d %5.1f%%  %s", name, n, share*100, strings.Repeat("#", int(share*30)))
+	}
+
+	line("")
+	line("Press ctrl+c to stop the run (the summary is printed at the end)")
+
+	d.mu.Lock()
+	if len(d.lines) > 0 {
+		line("")
+		for _, m := range d.lines {
+			line("%s", m)
+		}
+	}
+	d.mu.Unlock()
+
+	b.WriteString(clearScreen)
+
+	io.WriteString(d.out, b.String())
+}
+
+// truncate cuts s to at most width characters (it's left as is if width is 0, i.e. unknown)
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
+// latencyRange returns the lowest and highest (non-zero) latency of a sparkline
+func latencyRange(latencies []time.Duration) (lo, hi time.Duration) {
+	for _, l := range latencies {
+		if l == 0 {
+			continue
+		}
+		if lo == 0 || l < lo {
+			lo = l
+		}
+		hi = max(hi, l)
+	}
+	return lo, hi
+}
+
+// sparkline draws the latencies as a line of block characters scaled between their lowest and highest value
+// (a refresh without completed requests is left blank)
+func sparkline(latencies []time.Duration) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	lo, hi := latencyRange(latencies)
+
+	var b strings.Builder
+	for _, l := range latencies {
+		switch {
+		case l == 0:
+			b.WriteRune(' ')
+		case hi == lo:
+			b.WriteRune(blocks[len(blocks)/2])
+		default:
+			i := int(float64(l-lo) / float64(hi-lo) * float64(len(blocks)-1))
+			b.WriteRune(blocks[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+func TestTruncate(t *testing.T) {
+
+	testCases := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{s: "Progress:   12 requests", width: 0, want: "Progress:   12 requests"},
+		{s: "Progress:   12 requests", width: 8, want: "Progress"},
+		{s: "Progress", width: 8, want: "Progress"},
+		{s: "    ▁▂▃▄▅▆▇█", width: 6, want: "    ▁▂"}, // (cut by characters, not bytes)
+	}
+
+	for _, tt := range testCases {
+		if got := truncate(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d): got = %q, want = %q\n", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+// test that each refresh draws the average latency of the requests completed since the previous one
+func TestDashboardLatency(t *testing.T) {
+
+	var out strings.Builder
+	d := newDashboard(&out, 10, 0, hit.NewSummarizer(), nil)
+	d.start, d.lastDraw = time.Now(), time.Now()
+
+	d.add(hit.Result{Status: http.StatusOK, Duration: 10 * time.Millisecond})
+	d.add(hit.Result{Status: http.StatusOK, Duration: 30 * time.Millisecond})
+	d.add(hit.Result{Status: http.StatusOK, Duration: time.Second, Abandoned: true}) // (not counted)
+	d.draw()
+	d.draw() // no requests completed
+
+	want := []time.Duration{20 * time.Millisecond, 0}
+	if len(d.latencies) != len(want) || d.latencies[0] != want[0] || d.latencies[1] != want[1] {
+		t.Errorf("latencies: got = %v, want = %v\n", d.latencies, want)
+	}
+	if d.done.Load() != 3 {
+		t.Errorf("done: got = %d, want = 3\n", d.done.Load())
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// reportTemplate is the html report of a run: a single self-contained page
+// (the styles are inline and the charts are inline svg, so that it can be shared as a single file)
+//
+//go:embed report.html
+var reportTemplate string
+
+var reportPage = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string { return d.Round(10 * time.Microsecond).String() },
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.1f%%", v)
+	},
+}).Parse(reportTemplate))
+
+// htmlReport is the data of the html report of a run
+type htmlReport struct {
+	Info      hit.RunInfo
+	Summary   hit.Summary
+	Generated time.Time
+
+	Percentiles []percentileRow
+	Statuses    []statusRow
+	Targets     []targetRow
+
+	// the charts (inline svg)
+	PercentileChart, HistogramChart           template.HTML
+	ThroughputChart, ErrorChart, LatencyChart template.HTML
+	Interval                                  time.Duration
+}
+
+type percentileRow struct {
+	Name  string
+	Value time.Duration
+}
+
+type statusRow struct {
+	Code  string
+	Count int
+	Ratio float64 // percentage of the requests
+}
+
+type targetRow struct {
+	Name string
+	hit.Summary
+}
+
+// reportPercentiles are the percentiles of the latency shown in the html report
+var reportPercentiles = []float64{50, 75, 90, 95, 99, 99.9, 100}
+
+// writeHTMLReport writes the html report of a run to w
+// built from the summary and the time series of its results (see runReport)
+func writeHTMLReport(w io.Writer, info hit.RunInfo, sum hit.Summary, series *hit.TimeSeries) error {
+	r := htmlReport{
+		Info:      info,
+		Summary:   sum,
+		Generated: time.Now(),
+		Interval:  series.Interval(),
+	}
+
+	// latency percentiles
+	var (
+		labels []string
+		values []float64
+	)
+	for _, p := range reportPercentiles {
+		name := fmt.Sprintf("p%g", p)
+		if p == 100 {
+			name = "max"
+		}
+		d := sum.Percentile(p)
+		r.Percentiles = append(r.Percentiles, percentileRow{Name: name, Value: d})
+		labels = append(labels, name)
+		values = append(values, float64(d))
+	}
+	r.PercentileChart = barChart(labels, values, formatDuration, "#4e79a7")
+
+	// latency histogram (on a log scale, so that the long tail is visible)
+	labels, values = histogramBins(sum.Histogram, 30)
+	r.HistogramChart = barChart(labels, values, formatCount, "#59a14f")
+
+	// time series
+	points := series.Points()
+	var (
+		elapsed                 []string
+		rps, errs, avg, slowest []float64
+	)
+	for _, p := range points {
+		elapsed = append(elapsed, p.Time.Sub(points[0].Time).String())
+		rps = append(rps, p.RPS)
+		errs = append(errs, float64(p.Errors))
+		avg = append(avg, float64(p.Average))
+		slowest = append(slowest, float64(p.Slowest))
+	}
+	r.ThroughputChart = lineChart(elapsed, formatCount, chartSeries{"requests/s", "#4e79a7", rps})
+	r.ErrorChart = barChart(elapsed, errs, formatCount, "#e15759")
+	r.LatencyChart = lineChart(elapsed, formatDuration,
+		chartSeries{"average", "#4e79a7", avg},
+		chartSeries{"slowest", "#f28e2b", slowest},
+	)
+
+	// status breakdown
+	total := 0
+	for _, n := range sum.Statuses {
+		total += n
+	}
+	for _, code := range slices.Sorted(maps.Keys(sum.Statuses)) {
+		name := fmt.Sprint(code)
+		if code == 0 {
+			name = "no response"
+		}
+		n := sum.Statuses[code]
+		r.Statuses = append(r.Statuses, statusRow{Code: name, Count: n, Ratio: float64(n) / float64(total) * 100})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(sum.Targets)) {
+		r.Targets = append(r.Targets, targetRow{Name: name, Summary: sum.Targets[name]})
+	}
+
+	return reportPage.Execute(w, r)
+}
+
+// histogramBins regroups the buckets of a histogram into n bins of the same width on a log scale
+// (between the fastest and the slowest bucket) and returns their labels and counts
+func histogramBins(h hit.Histogram, n int) (labels []string, counts []float64) {
+	if len(h) == 0 {
+		return nil, nil
+	}
+
+	lo := math.Log(float64(max(h[0].Min, 1)))
+	hi := math.Log(float64(max(h[len(h)-1].Max, 1)))
+
+	// a single bin if all the durations are the same (e.g. a single bucket)
+	if hi <= lo {
+		var total float64
+		for _, b := range h {
+			total += float64(b.Count)
+		}
+		return []string{formatDuration(math.Exp(lo))}, []float64{total}
+	}
+	width := (hi - lo) / float64(n)
+
+	counts = make([]float64, n)
+	for _, b := range h {
+		mid := math.Log(float64(max(b.Min, 1))+float64(b.Max-b.Min)/2) - lo
+		i := min(max(int(mid/width), 0), n-1)
+		counts[i] += float64(b.Count)
+	}
+
+	labels = make([]string, n)
+	for i := range labels {
+		labels[i] = formatDuration(math.Exp(lo + width*float64(i)))
+	}
+	return labels, counts
+}
+
+func formatDuration(v float64) string {
+	d := time.Duration(v)
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+func formatCount(v float64) string {
+	if v >= 1000 {
+		return fmt.Sprintf("%.1fk", v/1000)
+	}
+	return fmt.Sprintf("%.4g", v)
+}
+
+// the size and margins of the charts (in svg units)
+const (
+	chartWidth, chartHeight = 760, 260
+	marginLeft, marginRight = 70, 10
+	marginTop, marginBottom = 10, 40
+)
+
+// chartSeries is a line of a line chart
+type chartSeries struct {
+	name   string
+	color  string
+	values []float64
+}
+
+// barChart returns an svg bar chart of values (one bar per label)
+func barChart(labels []string, values []float64, format func(float64) string, color string) template.HTML {
+	if len(values) == 0 {
+		return noData
+	}
+
+	var b strings.Builder
+	top := chartAxes(&b, labels, values, format)
+
+	plotW := float64(chartWidth - marginLeft - marginRight)
+	plotH := float64(chartHeight - marginTop - marginBottom)
+	bw := plotW / float64(len(values))
+
+	for i, v := range values {
+		h := v / top * plotH
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`,
+			float64(marginLeft)+float64(i)*bw+bw*0.1, float64(marginTop)+plotH-h, bw*0.8, h, color,
+			template.HTMLEscapeString(labels[i]), template.HTMLEscapeString(format(v)))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// lineChart returns an svg line chart of series (with a point per label)
+func lineChart(labels []string, format func(float64) string, series ...chartSeries) template.HTML {
+	if len(labels) == 0 {
+		return noData
+	}
+
+	var all []float64
+	for _, s := range series {
+		all = append(all, s.values...)
+	}
+
+	var b strings.Builder
+	top := chartAxes(&b, labels, all, format)
+
+	plotW := float64(chartWidth - marginLeft - marginRight)
+	plotH := float64(chartHeight - marginTop - marginBottom)
+	step := plotW / float64(len(labels))
+
+	for i, s := range series {
+		var pts []string
+		for j, v := range s.values {
+			x := float64(marginLeft) + step*(float64(j)+0.5)
+			y := float64(marginTop) + plotH - v/top*plotH
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(pts, " "), s.color)
+
+		// legend
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/><text x="%d" y="%d" class="legend">%s</text>`,
+			marginLeft+10+i*110, marginTop+2, s.color, marginLeft+24+i*110, marginTop+11, template.HTMLEscapeString(s.name))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// chartAxes writes the start of an svg chart with its axes and grid lines to b
+// and returns the top value of the y axis
+func chartAxes(b *strings.Builder, labels []string, values []float64, format func(float64) string) float64 {
+	top := slices.Max(values)
+	if top <= 0 {
+		top = 1
+	}
+
+	fmt.Fprintf(b, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg" role="img">`, chartWidth, chartHeight)
+
+	plotH := float64(chartHeight - marginTop - marginBottom)
+	plotW := float64(chartWidth - marginLeft - marginRight)
+
+	// horizontal grid lines (with the values of the y axis)
+	for i := range 5 {
+		y := float64(marginTop) + plotH - plotH*float64(i)/4
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/><text x="%d" y="%.1f" class="axis" text-anchor="end">%s</text>`,
+			marginLeft, y, chartWidth-marginRight, y, marginLeft-6, y+4, template.HTMLEscapeString(format(top*float64(i)/4)))
+	}
+
+	// labels of the x axis (at most 10 of them)
+	every := max(1, (len(labels)+9)/10)
+	step := plotW / float64(len(labels))
+	for i := 0; i < len(labels); i += every {
+		fmt.Fprintf(b, `<text x="%.1f" y="%d" class="axis" text-anchor="middle">%s</text>`,
+			float64(marginLeft)+step*(float64(i)+0.5), chartHeight-marginBottom+16, template.HTMLEscapeString(labels[i]))
+	}
+
+	return top
+}
+
+const noData = template.HTML(`<p class="empty">No data</p>`)
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// runImport runs the import sub command that writes a scenario file
+// built from a HAR file or a curl command line:
+//
+//	hit import har [-match regexp] [-o file] file.har
+//	hit import curl [-o file] 'curl ...'
+func runImport(args []string, stdout, stderr io.Writer) error {
+
+	flagSet := flag.NewFlagSet("hit import", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+
+	flagSet.Usage = func() {
+		fmt.Fprintf(
+			flagSet.Output(),
+			"usage: %[1]s har [options] file.har\n"+
+				"       %[1]s curl [options] 'curl ...' (reads the command from stdin if not given)\n"+
+				"options:\n",
+			flagSet.Name(),
+		)
+		flagSet.PrintDefaults()
+	}
+
+	var (
+		out   string
+		match string
+	)
+	flagSet.StringVar(&out, "o", "", "output scenario `file` (default stdout)")
+	flagSet.StringVar(&match, "match", "", "only import the har entries whose \"METHOD url\" matches the `regexp`")
+
+	if len(args) == 0 {
+		flagSet.Usage()
+		return errors.New("missing import source: har or curl")
+	}
+	source := args[0]
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var (
+		scenario *hit.Scenario
+		err      error
+	)
+
+	switch source {
+	case "har":
+		if flagSet.NArg() != 1 {
+			flagSet.Usage()
+			return errors.New("import har requires a single har file")
+		}
+
+		var filter *regexp.Regexp
+		if match != "" {
+			if filter, err = regexp.Compile(match); err != nil {
+				return fmt.Errorf("invalid value %q for flag -match: %w", match, err)
+			}
+		}
+
+		f, err := os.Open(flagSet.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		scenario, err = hit.ImportHAR(f, filter)
+		if err != nil {
+			return err
+		}
+
+	case "curl":
+		// the command can be given as a single (quoted) arg, as multiple args or via stdin
+		cmdline := strings.Join(flagSet.Args(), " ")
+		if cmdline == "" {
+			b, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			cmdline = string(b)
+		}
+
+		scenario, err = hit.ImportCurl(cmdline)
+		if err != nil {
+			return err
+		}
+
+	default:
+		flagSet.Usage()
+		return fmt.Errorf("unknown import source %q: want har or curl", source)
+	}
+
+	if out == "" {
+		return scenario.Write(stdout)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := scenario.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Wrote %d requests to scenario %q\n", len(scenario.Requests), out)
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+const testHAR = `{
+  "log": {
+    "entries": [
+      {"request": {"method": "GET", "url": "https://example.com/items", "headers": [{"name": "accept", "value": "application/json"}]}},
+      {"request": {"method": "POST", "url": "https://example.com/items", "headers": [], "postData": {"mimeType": "application/json", "text": "{}"}}},
+      {"request": {"method": "GET", "url": "https://cdn.example.com/app.js", "headers": []}}
+    ]
+  }
+}`
+
+func TestImport(t *testing.T) {
+
+	har := filepath.Join(t.TempDir(), "test.har")
+	if err := os.WriteFile(har, []byte(testHAR), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+		want []string // the "METHOD url" of each request of the scenario
+	}{
+		{name: "curl", args: []string{"curl", "curl -X POST -H 'Content-Type: application/json' -d '{}' http://localhost:8082/items"}, want: []string{"POST http://localhost:8082/items"}},
+		{name: "curl_args", args: []string{"curl", "curl", "http://localhost:8082/items"}, want: []string{"GET http://localhost:8082/items"}},
+		{name: "har", args: []string{"har", har}, want: []string{"GET https://example.com/items", "POST https://example.com/items", "GET https://cdn.example.com/app.js"}},
+		{name: "har_match", args: []string{"har", "-match", "^[A-Z]+ https://example.com/", har}, want: []string{"GET https://example.com/items", "POST https://example.com/items"}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			if err := runImport(tt.args, &stdout, &stderr); err != nil {
+				t.Fatalf("runImport() = %v; want no error\n", err)
+			}
+
+			scenario, err := hit.ReadScenario(strings.NewReader(stdout.String()))
+			if err != nil {
+				t.Fatalf("ReadScenario() = %v; want no error\n", err)
+			}
+			var got []string
+			for _, r := range scenario.Requests {
+				got = append(got, r.Method+" "+r.URL)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("requests: got = %q, want = %q\n", got, tt.want)
+			}
+		})
+	}
+}
+
+// test that the scenario is written to the -o file
+func TestImportOutput(t *testing.T) {
+
+	out := filepath.Join(t.TempDir(), "scenario.json")
+
+	var stdout, stderr strings.Builder
+	if err := runImport([]string{"curl", "-o", out, "curl http://localhost:8082/items"}, &stdout, &stderr); err != nil {
+		t.Fatalf("runImport() = %v; want no error\n", err)
+	}
+
+	if want := "Wrote 1 requests to scenario"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("stdout: got = %q, want it to contain %q\n", stdout.String(), want)
+	}
+
+	scenario, err := hit.LoadScenario(out)
+	if err != nil {
+		t.Fatalf("LoadScenario() = %v; want no error\n", err)
+	}
+	if got := scenario.Requests[0].URL; got != "http://localhost:8082/items" {
+		t.Errorf("url: got = %q, want = %q\n", got, "http://localhost:8082/items")
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		args []string
+		want string // the error
+	}{
+		{name: "no_source", args: nil, want: "missing import source"},
+		{name: "unknown_source", args: []string{"postman", "collection.json"}, want: `unknown import source "postman"`},
+		{name: "har_no_file", args: []string{"har"}, want: "import har requires a single har file"},
+		{name: "har_missing_file", args: []string{"har", filepath.Join(t.TempDir(), "missing.har")}, want: "no such file or directory"},
+		{name: "har_invalid_match", args: []string{"har", "-match", "(", "test.har"}, want: "invalid value \"(\" for flag -match"},
+		{name: "curl_unknown_option", args: []string{"curl", "curl --aws-sigv4 aws:amz http://localhost"}, want: "unknown curl option --aws-sigv4"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			err := runImport(tt.args, &stdout, &stderr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runImport() error = %v; want an error containing %q\n", err, tt.want)
+			}
+		})
+	}
+}
@@ -6,14 +6,18 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/faizan2786/gobyexample/hit"
@@ -29,10 +33,44 @@ const logo = `
 
 // define variables for the command line args
 type argConfig struct {
-	url string
-	n   int
-	c   int
-	rps int
+	url      string
+	n        int
+	c        int
+	rps      int
+	buffer   int        // requests and results buffered between the stages of the run (high-throughput mode)
+	targets  targetList // weighted targets (used instead of url when given)
+	scenario string     // scenario file of weighted targets (used instead of url when given)
+	method   string
+	header   http.Header
+	body     string
+	literal  bool // send the url, headers and body as is (not as templates)
+
+	warmup warmup        // warm-up requests (or duration) excluded from the summary
+	grace  time.Duration // time given to the in-flight requests to finish when the run is interrupted
+
+	replay string  // access log to replay against the url (instead of sending n requests)
+	speed  float64 // replay speed factor (e.g. 2 replays twice as fast)
+
+	dataFile    string       // csv or jsonl file of records for the templates
+	dataMode    hit.FeedMode // how the records are assigned to the requests
+	dataRecycle bool         // restart from the first record when the records run out
+
+	auth authSpec // authentication of the requests
+
+	debug       int    // number of first request/response exchanges dumped
+	debugFailed bool   // also dump every failing exchange
+	debugOut    string // file to dump the exchanges to (stderr if empty)
+
+	metricsAddr   string        // address to serve the prometheus metrics on (e.g. ":9100")
+	metricsLinger time.Duration // how long the final metrics are served after the run (so that they're scraped)
+	trace         bool          // inject a traceparent and X-Request-ID into each request
+
+	out        string        // run file to stream the results to (for "hit report")
+	thresholds thresholdList // pass/fail criteria of the run
+
+	dashboard bool // show a live dashboard of the run when stdout is a terminal
+
+	selfBench bool // measure the overhead of the client with no-op requests (instead of sending them)
 }
 
 // define a struct to hold the configurable env parameters for the run method
@@ -65,65 +103,398 @@ func main() {
 // i.e. using custom stdout and stderr such as string builder to capture messages during testing)
 func run(e *env) error {
 
+	// run a sub command (e.g. "hit import curl ...")
+	if len(e.args) > 1 {
+		switch e.args[1] {
+		case "import":
+			return runImport(e.args[2:], e.stdout, e.stderr)
+		case "report":
+			return runReport(e.args[2:], e.stdout, e.stderr)
+		case "compare":
+			return runCompare(e.args[2:], e.stdout, e.stderr)
+		}
+	}
+
 	config := argConfig{
-		n: 1000,
-		c: 1,
+		n:         1000,
+		c:         1,
+		method:    http.MethodGet,
+		header:    http.Header{},
+		speed:     1,
+		dashboard: true,
 	}
 
 	if err := parseArgs(e.args[1:], &config, e.stderr); err != nil {
 		return err
 	}
 
-	fmt.Fprintf(e.stdout, "%s\nSending %d requests to %q (concurrency=%d)\n", logo, config.n, config.url, config.c)
+	switch {
+	case config.selfBench:
+		fmt.Fprintf(e.stdout, "%s\nBenchmarking the client with %d no-op requests (concurrency=%d, buffer=%d)\n", logo, config.n, config.c, config.buffer)
+	case config.replay != "":
+		fmt.Fprintf(e.stdout, "%s\nReplaying %q to %q at %gx speed (concurrency=%d)\n", logo, config.replay, config.url, config.speed, config.c)
+	case config.scenario != "":
+		fmt.Fprintf(e.stdout, "%s\nSending %d requests from scenario %q (concurrency=%d)\n", logo, config.n, config.scenario, config.c)
+	case len(config.targets) > 0:
+		fmt.Fprintf(e.stdout, "%s\nSending %d requests to %d targets (concurrency=%d)\n", logo, config.n, len(config.targets), config.c)
+	default:
+		fmt.Fprintf(e.stdout, "%s\nSending %d requests to %q (concurrency=%d)\n", logo, config.n, config.url, config.c)
+	}
 
 	if e.testMode {
 		return nil
 	}
 
+	if config.selfBench {
+		return runSelfBench(config, e.stdout)
+	}
+
 	// run the actual hit client
-	err := runHit(config, e.stdout)
+	err := runHit(config, e.stdout, e.stderr)
 
 	return err
 }
 
 // run the HIT client with given args and print the requests summary
 // (HIT client will send N requests to the server and measure its performance)
-func runHit(config argConfig, stdout io.Writer) error {
+func runHit(config argConfig, stdout, stderr io.Writer) error {
+
+	opts := hit.Options{
+		Grace:          config.grace,
+		Concurrency:    config.c,
+		RPS:            config.rps,
+		Buffer:         config.buffer,
+		Warmup:         config.warmup.n,
+		WarmupDuration: config.warmup.d,
+		Monitor:        &hit.ClientMonitor{}, // warns if the client is the bottleneck
+		Auth:           config.auth.auth,
+		Trace:          config.trace,
+		ReportSkipped:  true, // the summary of a cancelled run is flagged as partial
+	}
 
-	// define a new HTTP GET request
-	req, err := http.NewRequest(http.MethodGet, config.url, http.NoBody)
-	if err != nil {
-		return fmt.Errorf("error while creating a new http request: %w", err)
+	// the consumers of the results as they are delivered
+	var onResult []func(hit.Result)
+
+	// the summarizer is fed as the results arrive
+	// (so that a partial summary can be printed if the run is force stopped)
+	sz := hit.NewSummarizer()
+
+	// pause/resume the requests with the Enter key or SIGUSR1/SIGUSR2
+	opts.Pauser = &hit.Pauser{}
+
+	// show a live dashboard of the run when stdout is a terminal
+	// (the plain summary is printed at the end either way)
+	// The messages written to stderr are shown in the dashboard if it's the same terminal,
+	// but there is no dashboard if the exchanges are dumped to it (they'd garble each other).
+	dumpStderr := (config.debug > 0 || config.debugFailed) && config.debugOut == ""
+	var dash *dashboard
+	if config.dashboard && isTerminal(stdout) && !(dumpStderr && sameTerminal(stdout, stderr)) {
+		dash = newDashboard(stdout, config.plannedRequests(), config.rps, sz, opts.Pauser)
+		opts.Middleware = append(opts.Middleware, dash.middleware) // (before Dump, which must be the innermost middleware)
+		onResult = append(onResult, dash.add)
+		if sameTerminal(stdout, stderr) {
+			stderr = dash.messages(stderr)
+		}
+	}
+
+	// serve the live metrics of the results
+	if config.metricsAddr != "" {
+		metrics := hit.NewMetrics()
+		stopMetrics, err := serveMetrics(config.metricsAddr, metrics, config.metricsLinger, stderr)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+		onResult = append(onResult, metrics.Add)
+	}
+
+	// stream the results to a run file (to report them again with "hit report")
+	var (
+		runFile *hit.RunWriter
+		runOut  *os.File // (closed by a forced exit as well)
+	)
+	if config.out != "" {
+		f, err := os.Create(config.out)
+		if err != nil {
+			return fmt.Errorf("error while creating the run file: %w", err)
+		}
+		defer f.Close()
+		runOut = f
+
+		info := hit.NewRunInfo(config.runTarget(), config.plannedRequests(), opts)
+		runFile, err = hit.NewRunWriter(f, info)
+		if err != nil {
+			return err
+		}
+		onResult = append(onResult, runFile.Add)
 	}
 
-	opts := hit.Options{Concurrency: config.c, RPS: config.rps}
+	// dump the request/response exchanges (e.g. to see why the target returns unexpected 400s)
+	if config.debug > 0 || config.debugFailed {
+		w := stderr
+		if config.debugOut != "" {
+			f, err := os.Create(config.debugOut)
+			if err != nil {
+				return fmt.Errorf("error while creating the debug file: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		opts.Middleware = append(opts.Middleware, hit.Dump(w, hit.DumpOptions{First: config.debug, Failed: config.debugFailed}))
+	}
+
+	// stop refreshing the token in the background at the end of the run
+	if c, ok := opts.Auth.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	if config.dataFile != "" {
+		feeder, err := hit.OpenFeeder(config.dataFile, config.dataMode, config.dataRecycle)
+		if err != nil {
+			return fmt.Errorf("error while opening the data file: %w", err)
+		}
+		defer feeder.Close()
+		opts.Feeder = feeder
+	}
 
-	// derive a signal notification context to catch os interrupt signals (e.g., SIGINT - generally caused by ctrl+c press)
-	// this will cause the go runtime to catch interrupt signal and cancel the context (i.e. notify)
-	// However, the go runtime will continue listening for the signal until the stop() function is called.
-	// Hence, stop() must be called as soon as we finish the termination on first signal.
+	pauseOnEnter(opts.Pauser, stderr)
+	stopPause := pauseOnSignal(opts.Pauser, stderr)
+	defer stopPause()
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if len(onResult) > 0 {
+		opts.Hooks.OnResult = func(r hit.Result) {
+			for _, f := range onResult {
+				f(r)
+			}
+		}
+	}
+
+	// derive a context that is cancelled on the first os interrupt signal (e.g., SIGINT - generally caused by ctrl+c press)
+	// a second interrupt prints the partial summary and exits immediately (even if requests are stuck)
+	ctx, stop := interruptContext(context.Background(), stderr, func() {
+		if dash != nil {
+			dash.stop()
+		}
+		fmt.Fprintln(stdout, "\nForced exit: the summary is partial")
+		printSummary(summary(sz, config.plannedRequests()), stdout)
+
+		// (os.Exit skips the deferred calls) save the results received so far
+		if runFile != nil {
+			if err := runFile.Flush(); err != nil {
+				fmt.Fprintln(stderr, err)
+			} else {
+				fmt.Fprintf(stdout, "\nPartial results saved to %q (see \"hit report\")\n", config.out)
+			}
+			runOut.Close()
+		}
+		os.Exit(1)
+	})
 	defer stop()
 
-	/*
-		// call sendN and print the result
-		results, err := hit.SendN(ctx, config.n, opts, req)
+	if dash != nil {
+		dash.run()
+		defer dash.stop()
+	}
+
+	var results hit.Results
+	if config.replay != "" {
+		// replay the access log against the url
+		f, err := os.Open(config.replay)
+		if err != nil {
+			return fmt.Errorf("error while opening the access log: %w", err)
+		}
+		defer f.Close()
+
+		format := hit.LogCommon
+		if ext := filepath.Ext(config.replay); ext == ".jsonl" || ext == ".ndjson" {
+			format = hit.LogJSONL
+		}
+
+		results, err = hit.Replay(ctx, opts, config.url, hit.ReadAccessLog(f, format), config.speed)
+		if err != nil {
+			return fmt.Errorf("error while replaying requests: %w", err)
+		}
+	} else {
+		targets, err := config.requestTargets()
+		if err != nil {
+			return err
+		}
+
+		// call sendTargets
+		results, err = hit.SendTargets(ctx, config.n, opts, targets...)
 		if err != nil {
 			return fmt.Errorf("error while sending requests: %w", err)
 		}
-		printResults(config.n, results, stdout)
-	*/
+	}
+
+	// calculate the summary
+	for r := range results {
+		sz.Add(r)
+	}
+	if dash != nil {
+		dash.stop()
+	}
+	printSummary(summary(sz, config.plannedRequests()), stdout)
+	printClient(opts.Monitor.Stats(), stdout)
+
+	if runFile != nil {
+		if err := runFile.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "\nResults saved to %q (see \"hit report\")\n", config.out)
+	}
+
+	// returns an error if context was cancelled (for some reason)
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	return checkThresholds(config.thresholds, sz.Summary(), stdout)
+}
+
+// runTarget returns what the requests are sent to (recorded in the run file)
+func (config argConfig) runTarget() string {
+	switch {
+	case config.replay != "":
+		return config.replay + " replayed to " + config.url
+	case config.scenario != "":
+		return config.scenario
+	case len(config.targets) > 0:
+		return config.targets.String()
+	default:
+		return strings.ToUpper(config.method) + " " + config.url
+	}
+}
+
+// plannedRequests returns the number of requests of the run (0 if unknown, i.e. a replay)
+func (config argConfig) plannedRequests() int {
+	if config.replay != "" {
+		return 0
+	}
+	return config.n
+}
+
+// summary returns the summary of a run of planned requests (0 if unknown, i.e. a replay)
+// the planned requests of a replay aren't counted from its results (the rest of a cancelled replay is a single skipped result)
+func summary(sz *hit.Summarizer, planned int) hit.Summary {
+	sum := sz.Summary()
+	if planned == 0 {
+		sum.Planned = 0
+	}
+	return sum
+}
 
-	// call sendN and calculate the summary
-	results, err := hit.SendN(ctx, config.n, opts, req)
+// runSelfBench measures the maximum request rate of the client (nothing is sent over the network)
+// e.g. to check that the client isn't the bottleneck of a run
+func runSelfBench(config argConfig, stdout io.Writer) error {
+
+	opts := hit.Options{
+		Concurrency: config.c,
+		Buffer:      config.buffer,
+	}
+
+	b, err := hit.SelfBenchmark(context.Background(), config.n, opts)
 	if err != nil {
-		return fmt.Errorf("error while sending requests: %w", err)
+		return fmt.Errorf("error while benchmarking the client: %w", err)
 	}
-	summary := hit.Summarize(results)
-	printSummary(summary, stdout)
 
-	return ctx.Err() // returns an error if context was cancelled (for some reason) otherwise, returns nil
+	fmt.Fprintf(stdout, `
+Self-benchmark:
+    Requests: %d
+    Duration: %s
+    Max rate: %.0f requests/s
+    Allocs:   %.1f per request (%.0f bytes)
+`,
+		b.Requests,
+		b.Duration.Round(time.Millisecond),
+		b.Rate,
+		b.AllocsPerRequest,
+		b.BytesPerRequest,
+	)
+
+	return nil
+}
+
+// serveMetrics serves the metrics in the prometheus text format at addr/metrics until stop is called.
+// stop keeps serving the final metrics for up to linger (so that the last scrape sees the end of the run)
+// i.e. until the first scrape after the run is served.
+func serveMetrics(addr string, metrics *hit.Metrics, linger time.Duration, stderr io.Writer) (stop func(), err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error while listening for the metrics: %w", err)
+	}
+
+	var (
+		ended   atomic.Bool           // the run has ended
+		scraped = make(chan struct{}) // closed once a scrape after the run is served
+		once    sync.Once
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		metrics.ServeHTTP(w, req)
+		if ended.Load() {
+			once.Do(func() { close(scraped) })
+		}
+	}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go server.Serve(ln)
+	fmt.Fprintf(stderr, "Serving metrics on http://%s/metrics\n", ln.Addr())
+
+	return func() {
+		ended.Store(true)
+		if linger > 0 {
+			fmt.Fprintf(stderr, "Serving the final metrics for up to %s, until they're scraped (press ctrl+c to exit)\n", linger)
+			select {
+			case <-scraped:
+			case <-time.After(linger):
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+		}
+	}, nil
+}
+
+// requestTargets returns the targets to send the requests to
+// (a single unnamed target for the url if no targets are given)
+// The url, headers and body are templates rendered for each request (see [hit.RequestTemplate])
+func (config argConfig) requestTargets() ([]hit.Target, error) {
+	if config.scenario != "" {
+		s, err := hit.LoadScenario(config.scenario)
+		if err != nil {
+			return nil, fmt.Errorf("error while loading the scenario: %w", err)
+		}
+		return s.Targets()
+	}
+
+	rawURL, header, body := config.url, config.header, config.body
+	if config.literal {
+		rawURL, header, body = hit.EscapeTemplate(rawURL), escapeHeader(header), hit.EscapeTemplate(body)
+	}
+
+	if len(config.targets) > 0 {
+		return config.targets.targets(header, body, config.literal)
+	}
+
+	tmpl, err := hit.NewRequestTemplate(strings.ToUpper(config.method), rawURL, header, body)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating a request template: %w", err)
+	}
+	return []hit.Target{{Template: tmpl}}, nil
+}
+
+// escapeHeader returns a copy of the header with its values escaped (see -literal)
+func escapeHeader(header http.Header) http.Header {
+	escaped := make(http.Header, len(header))
+	for key, values := range header {
+		for _, v := range values {
+			escaped[key] = append(escaped[key], hit.EscapeTemplate(v))
+		}
+	}
+	return escaped
 }
 
 func printSummary(sum hit.Summary, stdout io.Writer) {
@@ -138,6 +509,7 @@ Summary:
     Fastest:  %s
     Slowest:  %s
     Average:  %s
+    Latency:  p50 %s, p90 %s, p95 %s, p99 %s
 `,
 		sum.Success,
 		math.Round(sum.RPS),
@@ -148,43 +520,186 @@ Summary:
 		sum.Fastest.Round(time.Millisecond),
 		sum.Slowest.Round(time.Millisecond),
 		sum.Average.Round(time.Millisecond),
+		sum.Percentile(50).Round(time.Millisecond),
+		sum.Percentile(90).Round(time.Millisecond),
+		sum.Percentile(95).Round(time.Millisecond),
+		sum.Percentile(99).Round(time.Millisecond),
 	)
+
+	if len(sum.Statuses) > 0 {
+		var statuses []string
+		for _, code := range slices.Sorted(maps.Keys(sum.Statuses)) {
+			name := strconv.Itoa(code)
+			if code == 0 {
+				name = "no response"
+			}
+			statuses = append(statuses, fmt.Sprintf("%s: %d", name, sum.Statuses[code]))
+		}
+		fmt.Fprintf(stdout, "    Statuses: %s\n", strings.Join(statuses, ", "))
+	}
+
+	// flag a partial summary of a cancelled run
+	if sum.Partial {
+		planned := "unknown"
+		if sum.Planned > 0 {
+			planned = strconv.Itoa(sum.Planned)
+		}
+		fmt.Fprintf(stdout, `
+    Partial:  yes, the run was cancelled (%v)
+    Planned:  %s
+    Sent:     %d (%d completed, %d abandoned in flight at the end of the grace period)
+`,
+			sum.Cause,
+			planned,
+			sum.Sent,
+			sum.Requests,
+			sum.Abandoned,
+		)
+	}
+
+	if sum.AuthErrors > 0 {
+		fmt.Fprintf(stdout, "    Auth:     %d requests failed to authenticate (not sent)\n", sum.AuthErrors)
+	}
+
+	if w := sum.Warmup; w != nil {
+		fmt.Fprintf(stdout, "    Warm-up:  %d requests, %d errors, %s average (excluded from the summary)\n",
+			w.Requests,
+			w.Errors,
+			w.Average.Round(time.Millisecond),
+		)
+	}
+
+	if sum.MaxLag > 0 {
+		fmt.Fprintf(stdout, "    Drift:    %s average, %s max (behind the original schedule)\n",
+			sum.AverageLag.Round(time.Millisecond),
+			sum.MaxLag.Round(time.Millisecond),
+		)
+	}
+
+	printSlowest(sum.SlowestResults, stdout)
+	printErrors(sum.ErrorSamples, stdout)
+
+	if len(sum.Targets) == 0 {
+		return
+	}
+
+	// print the per target breakdown (sorted by target name)
+	fmt.Fprintf(stdout, "\nTargets:\n")
+	for _, name := range slices.Sorted(maps.Keys(sum.Targets)) {
+		t := sum.Targets[name]
+		fmt.Fprintf(stdout, "    %-20s Requests: %-6d Errors: %-6d RPS: %-8.1f Average: %s\n",
+			name,
+			t.Requests,
+			t.Errors,
+			t.RPS,
+			t.Average.Round(time.Millisecond),
+		)
+	}
 }
 
-// prints results as they come with a progress bar
-// it buffers the results and return an iterator for further consumption
-func printResults(n int, results hit.Results, stdout io.Writer) hit.Results {
+// printSlowest prints the details of the slowest requests
+// (and their IDs if they were traced, to look them up in the server's traces and logs)
+func printSlowest(slowest []hit.Result, stdout io.Writer) {
+	if len(slowest) == 0 {
+		return
+	}
 
-	res := make([]hit.Result, 0, n)
-	curr := 0
-	for r := range results {
-		res = append(res, r)
-		curr += 1
-		printProgress(curr, n, stdout)
+	fmt.Fprintf(stdout, "\nSlowest requests:\n")
+	for _, r := range slowest {
+		fmt.Fprintf(stdout, "    %-8s %s\n", r.Duration.Round(time.Millisecond), requestDetails(r))
+	}
+}
+
+// printErrors prints the distinct errors of the requests (most frequent first)
+// with the details of the first request that failed with each of them
+func printErrors(samples []hit.ErrorSample, stdout io.Writer) {
+	if len(samples) == 0 {
+		return
 	}
 
-	return hit.Results(slices.Values(res))
+	samples = slices.Clone(samples)
+	slices.SortStableFunc(samples, func(a, b hit.ErrorSample) int {
+		return b.Count - a.Count
+	})
+
+	fmt.Fprintf(stdout, "\nErrors:\n")
+	for _, e := range samples {
+		fmt.Fprintf(stdout, "    %-6d %s (%s to %s)\n        first: %s\n",
+			e.Count,
+			e.Message,
+			e.First.Format(timeFormat),
+			e.Last.Format(timeFormat),
+			requestDetails(e.Result),
+		)
+	}
 }
 
-func printProgress(c int, n int, stdout io.Writer) {
+// timeFormat is the format of the timestamps of the requests
+const timeFormat = "15:04:05.000"
 
-	if c <= 0 || n <= 0 {
+// requestDetails returns the details of a request to find it in the server's logs
+// e.g. "200 GET http://localhost:8080/items at 12:00:01.123 (#42, worker 3) request-id=... trace-id=..."
+func requestDetails(r hit.Result) string {
+	status := "---" // the request failed before receiving a response
+	if r.Status != 0 {
+		status = strconv.Itoa(r.Status)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", status, r.Method, r.URL)
+	if r.Target != "" {
+		fmt.Fprintf(&b, " [%s]", r.Target)
+	}
+	if !r.Start.IsZero() {
+		fmt.Fprintf(&b, " at %s", r.Start.Format(timeFormat))
+	}
+	fmt.Fprintf(&b, " (#%d, worker %d)", r.Seq, r.Worker)
+	if r.RequestID != "" {
+		fmt.Fprintf(&b, "  request-id=%s trace-id=%s", r.RequestID, r.TraceID)
+	}
+	return b.String()
+}
+
+// printClient prints the resource usage of the client during the run
+// (and a warning if the client was likely the bottleneck)
+func printClient(st hit.ClientStats, stdout io.Writer) {
+	if len(st.Samples) == 0 {
 		return
 	}
 
+	fmt.Fprintf(stdout, "\nClient:\n    CPU:        %.1f average, %.1f max (of %d cores)\n    Goroutines: %d max\n    GC pauses:  %s\n    Sched lag:  %s max\n",
+		st.CPU,
+		st.MaxCPU,
+		st.Cores,
+		st.MaxGoroutines,
+		st.GCPause.Round(time.Microsecond),
+		st.MaxSchedLag.Round(time.Millisecond),
+	)
+
+	if st.Saturated {
+		fmt.Fprintf(stdout, "\nWarning: %s\n", st.Warning)
+	}
+}
+
+// progressBar returns a progress bar of c requests of n, e.g. "[====    ] 500/1000 (50%)"
+// (it's drawn by the dashboard)
+func progressBar(c int, n int) string {
+
+	if n <= 0 {
+		return ""
+	}
+	c = min(max(c, 0), n)
+
 	const width int = 40
 	filled := c * width / n
 
-	// build string once and then print
-	bar := "[" +
+	// build string once
+	return "[" +
 		strings.Repeat("=", filled) +
 		strings.Repeat(" ", width-filled) +
 		"] " +
 		fmt.Sprintf("%d/%d", c, n) +
 		fmt.Sprintf(" (%d%%)", c*100/n) // print progress in percentage
-
-	// \r moves cursor to start of the line
-	fmt.Fprintf(stdout, "\r%s", bar)
 }
 
 // function to parse command line args and assigned them to a config variable (using the flag package)
@@ -199,7 +714,15 @@ func parseArgs(args []string, config *argConfig, stderr io.Writer) error {
 
 		fmt.Fprintf(
 			flagSet.Output(), // returns the writer we set above
-			"usage: %s [options] url\noptions:\n",
+			"usage: %[1]s [options] url\n"+
+				"       %[1]s [options] -t name:weight:[METHOD ]url [-t ...]\n"+
+				"       %[1]s [options] -scenario file\n"+
+				"       %[1]s [options] -replay access.log base-url\n"+
+				"       %[1]s [options] -self-bench\n"+
+				"       %[1]s import har|curl [options] ...\n"+
+				"       %[1]s report [options] run-file\n"+
+				"       %[1]s compare [options] base-run-file new-run-file\n"+
+				"options:\n",
 			flagSet.Name(),
 		)
 
@@ -212,6 +735,39 @@ func parseArgs(args []string, config *argConfig, stderr io.Writer) error {
 	flagSet.Var(asPositiveInt(&config.c), "c", "concurrency level")
 	flagSet.Var(asPositiveInt(&config.n), "n", "number of requests to send")
 	flagSet.Var(asPositiveInt(&config.rps), "rps", "requests per second")
+	flagSet.Var(asPositiveInt(&config.buffer), "buffer", "`number` of requests and results buffered between the stages of the run (high-throughput mode, default unbuffered)")
+	flagSet.Var(&config.warmup, "warmup", "warm-up phase excluded from the summary: a `number` of requests (e.g. 100) or a duration (e.g. 10s)")
+	flagSet.DurationVar(&config.grace, "grace", config.grace, "`time` given to the in-flight requests to finish when the run is interrupted")
+	flagSet.Var(&config.targets, "t", "weighted `target` in the form name:weight:[METHOD ]url (repeatable)")
+	flagSet.StringVar(&config.scenario, "scenario", config.scenario, "scenario `file` of weighted targets (see \"hit import\")")
+	flagSet.StringVar(&config.replay, "replay", config.replay, "access log `file` (common/combined log format or .jsonl) to replay against the url with its original timing (-n is not used)")
+	flagSet.Float64Var(&config.speed, "speed", config.speed, "replay speed `factor` (e.g. 2 replays twice as fast)")
+	flagSet.StringVar(&config.method, "m", config.method, "http `method` of the requests to url")
+	flagSet.Var(headerList(config.header), "H", "request `header` in the form \"Key: value\" (repeatable)")
+	flagSet.StringVar(&config.body, "d", config.body, "request `body`")
+	flagSet.BoolVar(&config.literal, "literal", config.literal, "send the url, headers and body as is instead of as templates (e.g. a body with a literal {{)")
+	flagSet.StringVar(&config.dataFile, "data", config.dataFile, "csv or jsonl `file` of records for the templates (as {{.Data.field}})")
+	flagSet.Func("data-mode", "how the data records are assigned: sequential, random or unique (per worker) (default sequential)", func(s string) error {
+		modes := map[string]hit.FeedMode{"sequential": hit.FeedSequential, "random": hit.FeedRandom, "unique": hit.FeedUnique}
+		mode, ok := modes[s]
+		if !ok {
+			return errors.New("want sequential, random or unique")
+		}
+		config.dataMode = mode
+		return nil
+	})
+	flagSet.IntVar(&config.debug, "debug", config.debug, "dump the first `N` request/response exchanges (with the credentials redacted and the bodies cut)")
+	flagSet.BoolVar(&config.debugFailed, "debug-failed", config.debugFailed, "also dump every failing exchange (an error or a status code of 400 or above)")
+	flagSet.StringVar(&config.debugOut, "debug-out", config.debugOut, "`file` to dump the exchanges to (default stderr)")
+	flagSet.StringVar(&config.metricsAddr, "metrics-addr", config.metricsAddr, "`address` to serve the live prometheus metrics on (e.g. :9100)")
+	flagSet.DurationVar(&config.metricsLinger, "metrics-linger", config.metricsLinger, "how long to keep serving the final metrics after the run, until they're scraped (e.g. a scrape interval, default 0 to stop right away)")
+	flagSet.BoolVar(&config.trace, "trace", config.trace, "inject a W3C traceparent and an X-Request-ID into each request (listed with the slowest and failed requests)")
+	flagSet.Var(&config.auth, "auth", "authentication of the requests: bearer:TOKEN, basic:USER:PASSWORD, oauth2:CLIENT_ID:CLIENT_SECRET@TOKEN_URL or hmac:KEY_ID:SECRET (a secret can be read with env:NAME or file:PATH)")
+	flagSet.StringVar(&config.out, "o", config.out, "run `file` to stream the results to (to report them again with \"hit report\")")
+	flagSet.Var(&config.thresholds, "threshold", "pass/fail `criterion` of the run, e.g. p99<500ms, avg<100ms, rps>=100 or errors<1% (repeatable, exits with an error if missed)")
+	flagSet.BoolVar(&config.dashboard, "dashboard", config.dashboard, "show a live dashboard of the run when stdout is a terminal (-dashboard=false prints the summary only)")
+	flagSet.BoolVar(&config.selfBench, "self-bench", config.selfBench, "measure the maximum request rate and allocations of the client with no-op requests (nothing is sent)")
+	flagSet.BoolVar(&config.dataRecycle, "data-recycle", config.dataRecycle, "restart from the first data record when the records run out (instead of stopping)")
 
 	if err := flagSet.Parse(args); err != nil {
 		return err
@@ -237,17 +793,72 @@ func parseArgs(args []string, config *argConfig, stderr io.Writer) error {
 
 func validateArgs(config *argConfig) error {
 
-	// parse the provided url (using net's url package)
-	u, err := url.Parse(config.url)
-	if err != nil {
-		return fmt.Errorf("invalid value %q for url: %w", config.url, err)
+	if !hit.ValidMethod(config.method) {
+		return fmt.Errorf("invalid value %q for flag -m: requires a valid http method (e.g. GET or POST)", config.method)
 	}
 
-	if config.url == "" || u.Scheme == "" || u.Host == "" {
-		return fmt.Errorf("invalid value %q for url: requires a valid url with a scheme and host", config.url)
+	if config.debug < 0 {
+		return fmt.Errorf("value for flag -debug(=%d) can not be negative", config.debug)
 	}
 
-	if config.c > config.n {
+	// nothing is sent in the self-benchmark mode
+	if config.selfBench {
+		if config.url != "" || config.replay != "" || config.scenario != "" || len(config.targets) > 0 {
+			return fmt.Errorf("flag -self-bench can not be used together with a url or flag -replay, -scenario or -t")
+		}
+		if config.c > config.n {
+			return fmt.Errorf("value for flag -c(=%d) can not be greater than the value for flag -n(=%d)", config.c, config.n)
+		}
+		return nil
+	}
+
+	if config.replay != "" {
+		if config.scenario != "" || len(config.targets) > 0 {
+			return fmt.Errorf("flag -replay can not be used together with flag -scenario or -t")
+		}
+		if config.speed <= 0 {
+			return fmt.Errorf("value for flag -speed(=%v) should be greater than 0", config.speed)
+		}
+		// (the requests and their timing are defined by the access log)
+		if config.rps > 0 || len(config.header) > 0 || config.body != "" || config.dataFile != "" || !strings.EqualFold(config.method, http.MethodGet) {
+			return fmt.Errorf("flag -replay can not be used together with flag -rps, -H, -d, -m or -data (the requests are defined by the access log)")
+		}
+	}
+
+	// the url is not needed when the targets are given
+	if config.scenario != "" {
+		if config.url != "" || len(config.targets) > 0 {
+			return fmt.Errorf("flag -scenario can not be used together with a url or flag -t")
+		}
+		// (the requests are defined by the scenario file)
+		if len(config.header) > 0 || config.body != "" || !strings.EqualFold(config.method, http.MethodGet) {
+			return fmt.Errorf("flag -scenario can not be used together with flag -H, -d or -m (the requests are defined by the scenario file)")
+		}
+	} else if len(config.targets) > 0 {
+		if config.url != "" {
+			return fmt.Errorf("url %q can not be used together with flag -t", config.url)
+		}
+		// (each target has its own method)
+		if !strings.EqualFold(config.method, http.MethodGet) {
+			return fmt.Errorf("flag -m can not be used together with flag -t (the method is given per target)")
+		}
+		if err := config.targets.validate(); err != nil {
+			return err
+		}
+	} else {
+		// parse the provided url (using net's url package)
+		u, err := url.Parse(config.url)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for url: %w", config.url, err)
+		}
+
+		if config.url == "" || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid value %q for url: requires a valid url with a scheme and host", config.url)
+		}
+	}
+
+	// (the number of requests of a replay is the number of entries of the access log)
+	if config.replay == "" && config.c > config.n {
 		return fmt.Errorf("value for flag -c(=%d) can not be greater than the value for flag -n(=%d)", config.c, config.n)
 	}
 
@@ -280,6 +891,40 @@ func (p *PositiveInt) Set(s string) error {
 	return nil
 }
 
+// define a warm-up type that implements flag's Value interface
+// (to parse either a number of requests or a duration)
+type warmup struct {
+	n int           // number of warm-up requests
+	d time.Duration // duration of the warm-up phase
+}
+
+func (w *warmup) String() string {
+	if w.d > 0 {
+		return w.d.String()
+	}
+	return strconv.Itoa(w.n)
+}
+
+func (w *warmup) Set(s string) error {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return errors.New("value should not be negative")
+		}
+		*w = warmup{n: n}
+		return nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("value should be a number of requests or a duration")
+	}
+	if d < 0 {
+		return errors.New("value should not be negative")
+	}
+	*w = warmup{d: d}
+	return nil
+}
+
 // a helper function to wrap a pointer to int to a pointer to a PositiveInt
 func asPositiveInt(i *int) *PositiveInt {
 	return (*PositiveInt)(i) // the conversion works because both int and PositiveInt share same underlying type
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRun calls run with the given args in test mode (i.e. the args are validated but nothing is sent)
+// and returns its stdout and stderr
+func testRun(args ...string) (stdout, stderr string, err error) {
+	var out, errOut strings.Builder
+	e := &env{
+		stdout:   &out,
+		stderr:   &errOut,
+		args:     append([]string{"hit"}, args...), // run gets the entire os.Args (including the program name)
+		testMode: true,
+	}
+
+	err = run(e)
+	return out.String(), errOut.String(), err
+}
+
+func TestRunValid(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		args []string
+		want string // message printed on stdout
+	}{
+		{name: "url", args: []string{"http://localhost:8082"}, want: `Sending 1000 requests to "http://localhost:8082" (concurrency=1)`},
+		{name: "targets", args: []string{"-t", "reads:7:http://localhost/items", "-t", "writes:3:POST http://localhost/items"}, want: "Sending 1000 requests to 2 targets"},
+		{name: "scenario", args: []string{"-scenario", "scenario.json", "-n", "10"}, want: `Sending 10 requests from scenario "scenario.json"`},
+		{name: "replay", args: []string{"-replay", "access.log", "-speed", "2", "http://localhost:8082"}, want: `Replaying "access.log" to "http://localhost:8082" at 2x speed`},
+		{name: "replay_concurrency", args: []string{"-replay", "access.log", "-c", "2000", "http://localhost:8082"}, want: "concurrency=2000"}, // (-n isn't used)
+		{name: "self_bench", args: []string{"-self-bench", "-buffer", "64"}, want: "Benchmarking the client with 1000 no-op requests (concurrency=1, buffer=64)"},
+		{name: "auth_from_env", args: []string{"-auth", "bearer:env:HIT_TEST_TOKEN", "http://localhost:8082"}, want: "Sending 1000 requests"},
+	}
+
+	t.Setenv("HIT_TEST_TOKEN", "token")
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := testRun(tt.args...)
+			if err != nil {
+				t.Fatalf("run() = %v; want no error\n", err)
+			}
+			if stderr != "" {
+				t.Errorf("stderr: got = %q, want = \"\"\n", stderr)
+			}
+			if !strings.Contains(stdout, tt.want) {
+				t.Errorf("stdout: got = %q, want it to contain %q\n", stdout, tt.want)
+			}
+		})
+	}
+}
+
+// test the errors of the invalid (combinations of) args, the first error found is reported
+func TestRunInvalid(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		args []string
+		want string // the error
+	}{
+		{name: "no_url", args: nil, want: `invalid value "" for url`},
+		{name: "invalid_url", args: []string{"www.myurl.com"}, want: `invalid value "www.myurl.com" for url`},
+		{name: "url_before_concurrency", args: []string{"-c", "10", "-n", "5", "www.myurl.com"}, want: `invalid value "www.myurl.com" for url`},
+		{name: "concurrency", args: []string{"-c", "10", "-n", "5", "http://localhost"}, want: "value for flag -c(=10) can not be greater than the value for flag -n(=5)"},
+		{name: "targets_concurrency", args: []string{"-c", "10", "-n", "5", "-t", "a:1:http://localhost"}, want: "value for flag -c(=10)"},
+		{name: "non_positive_flag", args: []string{"-c", "0", "http://localhost"}, want: "value should be greater than 0"},
+		{name: "invalid_method", args: []string{"-m", "GE T", "http://localhost"}, want: `invalid value "GE T" for flag -m`},
+		{name: "negative_debug", args: []string{"-debug", "-1", "http://localhost"}, want: "value for flag -debug(=-1) can not be negative"},
+		{name: "self_bench_with_url", args: []string{"-self-bench", "http://localhost"}, want: "flag -self-bench can not be used together with a url"},
+		{name: "replay_with_targets", args: []string{"-replay", "access.log", "-t", "a:1:http://localhost"}, want: "flag -replay can not be used together with flag -scenario or -t"},
+		{name: "replay_speed", args: []string{"-replay", "access.log", "-speed", "0", "http://localhost"}, want: "value for flag -speed(=0) should be greater than 0"},
+		{name: "replay_with_rps", args: []string{"-replay", "access.log", "-rps", "10", "http://localhost"}, want: "flag -replay can not be used together with flag -rps"},
+		{name: "replay_with_data", args: []string{"-replay", "access.log", "-data", "users.csv", "http://localhost"}, want: "flag -replay can not be used together with flag -rps"},
+		{name: "scenario_with_url", args: []string{"-scenario", "scenario.json", "http://localhost"}, want: "flag -scenario can not be used together with a url or flag -t"},
+		{name: "scenario_with_header", args: []string{"-scenario", "scenario.json", "-H", "X-Tenant: acme"}, want: "flag -scenario can not be used together with flag -H, -d or -m"},
+		{name: "targets_with_url", args: []string{"-t", "a:1:http://localhost", "http://localhost"}, want: `url "http://localhost" can not be used together with flag -t`},
+		{name: "targets_with_method", args: []string{"-m", "POST", "-t", "a:1:http://localhost"}, want: "flag -m can not be used together with flag -t"},
+		{name: "targets_invalid", args: []string{"-t", "a:1:localhost"}, want: `invalid url "localhost" for target "a"`},
+		{name: "repeated_auth", args: []string{"-auth", "bearer:a", "-auth", "basic:user:pass", "http://localhost"}, want: "the authentication is already set"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := testRun(tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("run() error = %v; want an error containing %q\n", err, tt.want)
+			}
+			// the usage is printed after the error
+			if !strings.Contains(stderr, "usage:") {
+				t.Errorf("stderr: got = %q, want the usage\n", stderr)
+			}
+		})
+	}
+}
+
+func TestWarmupSet(t *testing.T) {
+
+	testCases := []struct {
+		value   string
+		want    warmup
+		wantErr bool
+	}{
+		{value: "100", want: warmup{n: 100}},
+		{value: "0", want: warmup{}},
+		{value: "10s", want: warmup{d: 10 * time.Second}},
+		{value: "-1", wantErr: true},
+		{value: "-1s", wantErr: true},
+		{value: "ten", wantErr: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.value, func(t *testing.T) {
+			var w warmup
+			err := w.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v; want error = %t\n", tt.value, err, tt.wantErr)
+			}
+			if w != tt.want {
+				t.Errorf("Set(%q): got = %+v, want = %+v\n", tt.value, w, tt.want)
+			}
+		})
+	}
+}
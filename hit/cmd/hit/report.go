@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// runReport runs the report sub command that reports a run again from its run file
+// (e.g. with other thresholds, without sending the requests again):
+//
+//	hit -o run.jsonl http://localhost:8082
+//	hit report [-interval 1s] [-threshold p99<500ms ...] [-html report.html] run.jsonl
+func runReport(args []string, stdout, stderr io.Writer) error {
+
+	flagSet := flag.NewFlagSet("hit report", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: %s [options] run-file\noptions:\n", flagSet.Name())
+		flagSet.PrintDefaults()
+	}
+
+	var (
+		interval   time.Duration
+		thresholds thresholdList
+		html       string
+	)
+	flagSet.DurationVar(&interval, "interval", time.Second, "`interval` of the time series")
+	flagSet.StringVar(&html, "html", "", "also write a self-contained html report to `file` (e.g. to share it)")
+	flagSet.Var(&thresholds, "threshold", "pass/fail `criterion` of the run, e.g. p99<500ms, avg<100ms, rps>=100 or errors<1% (repeatable)")
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return errors.New("missing run file")
+	}
+
+	f, err := os.Open(flagSet.Arg(0))
+	if err != nil {
+		return fmt.Errorf("error while opening the run file: %w", err)
+	}
+	defer f.Close()
+
+	info, results, err := hit.ReadRun(f)
+	if err != nil {
+		return fmt.Errorf("error while reading the run file: %w", err)
+	}
+
+	// the summary and the time series are built in a single pass over the results
+	sz := hit.NewSummarizer()
+	series := hit.NewTimeSeries(interval)
+	for r, err := range results {
+		if err != nil {
+			return fmt.Errorf("error while reading the run file: %w", err)
+		}
+		sz.Add(r)
+		series.Add(r)
+	}
+
+	printRunInfo(info, stdout)
+	printSummary(summary(sz, info.Options.Requests), stdout)
+	printTimeSeries(series, stdout)
+
+	if html != "" {
+		if err := saveHTMLReport(html, info, sz.Summary(), series); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "\nReport written to %q\n", html)
+	}
+
+	return checkThresholds(thresholds, sz.Summary(), stdout)
+}
+
+// saveHTMLReport writes the html report of a run to a file
+func saveHTMLReport(name string, info hit.RunInfo, sum hit.Summary, series *hit.TimeSeries) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("error while creating the html report: %w", err)
+	}
+	defer f.Close()
+
+	if err := writeHTMLReport(f, info, sum, series); err != nil {
+		return fmt.Errorf("error while writing the html report: %w", err)
+	}
+	return f.Close()
+}
+
+// printRunInfo prints the metadata of a run
+func printRunInfo(info hit.RunInfo, stdout io.Writer) {
+	opts := info.Options
+
+	fmt.Fprintf(stdout, "Run:\n    Target:   %s\n    Started:  %s\n    Options:  %d requests, concurrency=%d",
+		info.Target,
+		info.Start.Format(time.RFC3339),
+		opts.Requests,
+		opts.Concurrency,
+	)
+	if opts.RPS > 0 {
+		fmt.Fprintf(stdout, ", rps=%d", opts.RPS)
+	}
+	if opts.Buffer > 0 {
+		fmt.Fprintf(stdout, ", buffer=%d", opts.Buffer)
+	}
+	if opts.Warmup > 0 {
+		fmt.Fprintf(stdout, ", warmup=%d", opts.Warmup)
+	}
+	if opts.WarmupDuration > 0 {
+		fmt.Fprintf(stdout, ", warmup=%s", opts.WarmupDuration)
+	}
+	fmt.Fprintf(stdout, "\n    Version:  hit %s (%s)\n", info.Version, info.GoVersion)
+}
+
+// printTimeSeries prints the throughput, errors and latency of each interval of the run
+func printTimeSeries(series *hit.TimeSeries, stdout io.Writer) {
+	points := series.Points()
+	if len(points) == 0 {
+		return
+	}
+
+	fmt.Fprintf(stdout, "\nTime series (every %s):\n    %-10s %-10s %-8s %-10s %s\n", series.Interval(), "Elapsed", "RPS", "Errors", "Average", "Slowest")
+	for _, p := range points {
+		fmt.Fprintf(stdout, "    %-10s %-10.1f %-8d %-10s %s\n",
+			p.Time.Sub(points[0].Time),
+			p.RPS,
+			p.Errors,
+			p.Average.Round(time.Millisecond),
+			p.Slowest.Round(time.Millisecond),
+		)
+	}
+	if n := series.Dropped(); n > 0 {
+		fmt.Fprintf(stdout, "    (%d results left out as their end time is outside the span of %d intervals)\n", n, hit.MaxPoints)
+	}
+}
+
+// checkThresholds prints whether the summary meets each of the thresholds
+// and returns an error if it misses any of them
+func checkThresholds(thresholds thresholdList, sum hit.Summary, stdout io.Writer) error {
+	if len(thresholds) == 0 {
+		return nil
+	}
+
+	failed := 0
+	fmt.Fprintf(stdout, "\nThresholds:\n")
+	for _, t := range thresholds {
+		value, ok := t.Check(sum)
+		status := "pass"
+		if !ok {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(stdout, "    %-4s  %-20s (got %s)\n", status, t, t.Format(value))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d thresholds failed", failed, len(thresholds))
+	}
+	return nil
+}
+
+// define a threshold list type that implements flag's Value interface
+// (to collect the repeated -threshold flags)
+type thresholdList []hit.Threshold
+
+func (l *thresholdList) String() string {
+	var s []string
+	for _, t := range *l {
+		s = append(s, t.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+func (l *thresholdList) Set(s string) error {
+	t, err := hit.ParseThreshold(s)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, t)
+	return nil
+}
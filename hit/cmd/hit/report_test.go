@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// writeRunFile writes a run file with the given results in a temp dir and returns its path
+func writeRunFile(t *testing.T, name string, results []hit.Result) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rw, err := hit.NewRunWriter(f, hit.NewRunInfo("http://localhost:8082", len(results), hit.Options{Concurrency: 2}))
+	if err != nil {
+		t.Fatalf("NewRunWriter() = %v; want no error\n", err)
+	}
+	for _, r := range results {
+		rw.Add(r)
+	}
+	if err := rw.Flush(); err != nil {
+		t.Fatalf("Flush() = %v; want no error\n", err)
+	}
+	return path
+}
+
+// testResults returns n results (one every 100ms) with durations from base to base+9ms
+// and every 10th result failed with a 500
+func testResults(n int, base time.Duration) []hit.Result {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	results := make([]hit.Result, n)
+	for i := range results {
+		d := base + time.Duration(i%10)*time.Millisecond
+		results[i] = hit.Result{
+			Seq:      i,
+			Status:   200,
+			Duration: d,
+			Start:    start.Add(time.Duration(i) * 100 * time.Millisecond),
+		}
+		results[i].End = results[i].Start.Add(d)
+		if i%10 == 9 {
+			results[i].Status = 500
+		}
+	}
+	return results
+}
+
+func TestReport(t *testing.T) {
+
+	runFile := writeRunFile(t, "run.jsonl", testResults(50, 10*time.Millisecond))
+	html := filepath.Join(t.TempDir(), "report.html")
+
+	var stdout, stderr strings.Builder
+	err := runReport([]string{"-threshold", "p99<1s", "-threshold", "errors<20%", "-html", html, runFile}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("runReport() = %v; want no error\n", err)
+	}
+
+	for _, want := range []string{
+		"Target:   http://localhost:8082",
+		"50 requests, concurrency=2",
+		"Time series (every 1s)",
+		"pass  p99<1s",
+		"pass  errors<20%",
+		"Report written to",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout: got = %q, want it to contain %q\n", stdout.String(), want)
+		}
+	}
+
+	b, err := os.ReadFile(html)
+	if err != nil {
+		t.Fatalf("html report: %v\n", err)
+	}
+	if !strings.Contains(string(b), "http://localhost:8082") {
+		t.Errorf("html report: got no target, want = %q\n", "http://localhost:8082")
+	}
+}
+
+func TestReportInvalid(t *testing.T) {
+
+	runFile := writeRunFile(t, "run.jsonl", testResults(50, 10*time.Millisecond))
+
+	notRunFile := filepath.Join(t.TempDir(), "scenario.json")
+	if err := os.WriteFile(notRunFile, []byte(`{"requests": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+		want string // the error
+	}{
+		{name: "failed_threshold", args: []string{"-threshold", "p99<5ms", "-threshold", "errors<20%", runFile}, want: "1 of 2 thresholds failed"},
+		{name: "no_run_file", args: nil, want: "missing run file"},
+		{name: "missing_run_file", args: []string{filepath.Join(t.TempDir(), "missing.jsonl")}, want: "error while opening the run file"},
+		{name: "not_a_run_file", args: []string{notRunFile}, want: "error while reading the run file"},
+		{name: "invalid_threshold", args: []string{"-threshold", "p99", runFile}, want: "invalid value"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			err := runReport(tt.args, &stdout, &stderr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runReport() error = %v; want an error containing %q\n", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// errInterrupted is the cancellation cause of a run stopped by ctrl+c
+var errInterrupted = errors.New("interrupted")
+
+// interruptContext returns a context that is cancelled on the first interrupt signal (e.g. ctrl+c press)
+// so that the run stops gracefully. A second interrupt calls force (e.g. to exit immediately).
+// stop must be called to stop listening for the interrupts.
+func interruptContext(parent context.Context, stderr io.Writer, force func()) (ctx context.Context, stop func()) {
+
+	// Note: we don't use signal.NotifyContext here
+	// as it stops at the first signal (it can't tell a first and a second interrupt apart)
+
+	ctx, cancel := context.WithCancelCause(parent)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+
+	done := make(chan struct{})
+	go func() {
+		// 1st interrupt: stop the run gracefully
+		select {
+		case <-sig:
+		case <-done:
+			return
+		}
+		fmt.Fprintln(stderr, "\nStopping... (press ctrl+c again to exit immediately)")
+		cancel(errInterrupted)
+
+		// 2nd interrupt: exit without waiting for the in-flight requests
+		select {
+		case <-sig:
+			force()
+		case <-done:
+		}
+	}()
+
+	stop = func() {
+		signal.Stop(sig)
+		close(done)
+		cancel(nil)
+	}
+	return ctx, stop
+}
+
+// pauseOnEnter toggles the pauser each time the Enter key is pressed
+// (only if stdin is a terminal - e.g. not when the input is piped to hit)
+func pauseOnEnter(p *hit.Pauser, stderr io.Writer) {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if p.Paused() {
+				resume(p, stderr)
+			} else {
+				pause(p, stderr)
+			}
+		}
+	}()
+}
+
+func pause(p *hit.Pauser, stderr io.Writer) {
+	p.Pause()
+	fmt.Fprintln(stderr, "Paused (press Enter or send SIGUSR2 to resume)")
+}
+
+func resume(p *hit.Pauser, stderr io.Writer) {
+	p.Resume()
+	fmt.Fprintln(stderr, "Resumed")
+}
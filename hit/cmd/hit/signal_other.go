@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"io"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// pauseOnSignal does nothing as there are no SIGUSR1/SIGUSR2 signals on this platform
+// (the requests can still be paused with the Enter key).
+func pauseOnSignal(_ *hit.Pauser, _ io.Writer) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// pauseOnSignal pauses the requests on SIGUSR1 and resumes them on SIGUSR2
+// (e.g. kill -USR1 <pid>) until stop is called.
+func pauseOnSignal(p *hit.Pauser, stderr io.Writer) (stop func()) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for s := range sig {
+			if s == syscall.SIGUSR1 {
+				pause(p, stderr)
+			} else {
+				resume(p, stderr)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sig)
+		close(sig) // stop the goroutine (no more signals are delivered after signal.Stop)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// targetSpec is a named target given on the command line in the form:
+//
+//	name:weight:[METHOD ]url
+//
+// e.g. "reads:70:http://localhost:8082/items" or "writes:10:POST http://localhost:8082/items"
+type targetSpec struct {
+	name   string
+	weight int
+	method string
+	url    string
+}
+
+// targetList implements flag's Value interface to collect a repeated -t flag
+type targetList []targetSpec
+
+func (l *targetList) String() string {
+	specs := make([]string, len(*l))
+	for i, t := range *l {
+		specs[i] = fmt.Sprintf("%s:%d:%s %s", t.name, t.weight, t.method, t.url)
+	}
+	return strings.Join(specs, ", ")
+}
+
+func (l *targetList) Set(s string) error {
+
+	// split into at most 3 parts as the url itself contains colons
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return fmt.Errorf("want name:weight:[METHOD ]url")
+	}
+
+	weight, err := strconv.Atoi(parts[1])
+	if err != nil || weight <= 0 {
+		return fmt.Errorf("weight of target %q should be greater than 0", parts[0])
+	}
+
+	spec := targetSpec{name: parts[0], weight: weight, method: http.MethodGet, url: parts[2]}
+	if method, rawURL, ok := strings.Cut(parts[2], " "); ok {
+		spec.method = strings.ToUpper(method)
+		spec.url = strings.TrimSpace(rawURL)
+	}
+
+	*l = append(*l, spec)
+	return nil
+}
+
+// validate checks that each target has a valid url and a unique name.
+func (l targetList) validate() error {
+	names := make(map[string]bool, len(l))
+	for _, t := range l {
+		if names[t.name] {
+			return fmt.Errorf("duplicate target name %q", t.name)
+		}
+		names[t.name] = true
+
+		if !hit.ValidMethod(t.method) {
+			return fmt.Errorf("invalid method %q for target %q", t.method, t.name)
+		}
+
+		u, err := url.Parse(t.url)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid url %q for target %q: requires a valid url with a scheme and host", t.url, t.name)
+		}
+	}
+	return nil
+}
+
+// targets builds a templated [hit.Target] for each target spec
+// (the header and body templates are shared by all the targets, their urls are escaped if literal).
+func (l targetList) targets(header http.Header, body string, literal bool) ([]hit.Target, error) {
+	targets := make([]hit.Target, 0, len(l))
+	for _, t := range l {
+		rawURL := t.url
+		if literal {
+			rawURL = hit.EscapeTemplate(rawURL)
+		}
+		tmpl, err := hit.NewRequestTemplate(t.method, rawURL, header, body)
+		if err != nil {
+			return nil, fmt.Errorf("error while creating a request template for target %q: %w", t.name, err)
+		}
+		targets = append(targets, hit.Target{Name: t.name, Weight: t.weight, Template: tmpl})
+	}
+	return targets, nil
+}
+
+// headerList implements flag's Value interface to collect a repeated -H flag
+// in the form "Key: value"
+type headerList http.Header
+
+func (h headerList) String() string {
+	headers := make([]string, 0, len(h))
+	for key, values := range h {
+		for _, v := range values {
+			headers = append(headers, key+": "+v)
+		}
+	}
+	return strings.Join(headers, ", ")
+}
+
+func (h headerList) Set(s string) error {
+	key, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("want \"Key: value\"")
+	}
+	http.Header(h).Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	return nil
+}
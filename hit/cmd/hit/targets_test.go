@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTargetListSet(t *testing.T) {
+
+	testCases := []struct {
+		value   string
+		want    targetSpec
+		wantErr bool
+	}{
+		{value: "reads:70:http://localhost:8082/items", want: targetSpec{name: "reads", weight: 70, method: "GET", url: "http://localhost:8082/items"}},
+		{value: "writes:10:post http://localhost:8082/items", want: targetSpec{name: "writes", weight: 10, method: "POST", url: "http://localhost:8082/items"}},
+		{value: "reads:http://localhost", wantErr: true}, // no weight
+		{value: ":1:http://localhost", wantErr: true},    // no name
+		{value: "reads:0:http://localhost", wantErr: true},
+		{value: "reads:x:http://localhost", wantErr: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.value, func(t *testing.T) {
+			var l targetList
+			err := l.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v; want error = %t\n", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(l) != 1 || l[0] != tt.want {
+				t.Errorf("Set(%q): got = %+v, want = [%+v]\n", tt.value, l, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetListValidate(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		targets targetList
+		want    string // the error ("" if valid)
+	}{
+		{name: "valid", targets: targetList{{name: "a", weight: 1, method: "GET", url: "http://localhost/a"}, {name: "b", weight: 1, method: "PURGE", url: "http://localhost/b"}}},
+		{name: "duplicate_name", targets: targetList{{name: "a", weight: 1, method: "GET", url: "http://localhost/a"}, {name: "a", weight: 1, method: "GET", url: "http://localhost/b"}}, want: `duplicate target name "a"`},
+		{name: "invalid_method", targets: targetList{{name: "a", weight: 1, method: "GE(T", url: "http://localhost/a"}}, want: `invalid method "GE(T" for target "a"`},
+		{name: "invalid_url", targets: targetList{{name: "a", weight: 1, method: "GET", url: "/a"}}, want: `invalid url "/a" for target "a"`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.targets.validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("validate() = %v; want no error\n", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("validate() error = %v; want an error containing %q\n", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderListSet(t *testing.T) {
+
+	header := http.Header{}
+	h := headerList(header)
+
+	for _, v := range []string{"X-Tenant: acme", "accept:application/json", "X-Tenant: other", "X-Empty:"} {
+		if err := h.Set(v); err != nil {
+			t.Fatalf("Set(%q) = %v; want no error\n", v, err)
+		}
+	}
+
+	want := http.Header{"X-Tenant": {"acme", "other"}, "Accept": {"application/json"}, "X-Empty": {""}}
+	if len(header) != len(want) {
+		t.Fatalf("header: got = %v, want = %v\n", header, want)
+	}
+	for key, values := range want {
+		if got := header.Values(key); strings.Join(got, ",") != strings.Join(values, ",") {
+			t.Errorf("%s: got = %q, want = %q\n", key, got, values)
+		}
+	}
+
+	for _, v := range []string{"X-Tenant", ": value"} {
+		if err := h.Set(v); err == nil {
+			t.Errorf("Set(%q) error = <nil>; want an error\n", v)
+		}
+	}
+}
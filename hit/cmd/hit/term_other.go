@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// terminalWidth returns the number of columns of the terminal from $COLUMNS (0 if it isn't set)
+// as its size can't be queried on this platform
+func terminalWidth(_ io.Writer) int {
+	n, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
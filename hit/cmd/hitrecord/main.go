@@ -0,0 +1,243 @@
+// This program is a recording reverse proxy that sits in front of a (local) service.
+// It forwards the traffic to the service and records each request (and its response metadata)
+// into a JSONL replay file that the hit tool can replay with its original timing:
+//
+//	hitrecord -listen :8081 -o recording.jsonl http://localhost:8082
+//	hit -replay recording.jsonl http://localhost:8082
+//
+// The credentials (e.g. the Authorization and Cookie headers) are redacted from the recording by default.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// record is a single line of the replay file
+// (a [hit.LogEntry] with the metadata of the response)
+type record struct {
+	hit.LogEntry
+	Response response `json:"response"`
+}
+
+type response struct {
+	Status   int           `json:"status"`
+	Bytes    int64         `json:"bytes"`    // number of bytes of the response body
+	Duration time.Duration `json:"duration"` // time taken to serve the request (in nanoseconds)
+}
+
+// hopHeaders are the headers of the proxy's own connection (not recorded)
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length"}
+
+func main() {
+	if err := run(os.Args, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error while running the hitrecord tool: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+
+	flagSet := flag.NewFlagSet("hitrecord", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: %s [options] target-url\noptions:\n", flagSet.Name())
+		flagSet.PrintDefaults()
+	}
+
+	listen := flagSet.String("listen", ":8081", "`address` to listen on")
+	out := flagSet.String("o", "recording.jsonl", "replay `file` to record the requests to")
+	grace := flagSet.Duration("grace", 10*time.Second, "`time` given to the in-flight requests to finish on ctrl+c (they are recorded)")
+	redact := flagSet.String("redact", strings.Join(hit.DefaultRedact, ","), "comma-separated `headers` whose values are redacted from the recording (empty to record them as is)")
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	target, err := url.Parse(flagSet.Arg(0))
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		fmt.Fprintf(flagSet.Output(), "invalid value %q for target url: requires a valid url with a scheme and host\n", flagSet.Arg(0))
+		flagSet.Usage()
+		return errors.New("invalid target url")
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var redacted []string
+	if *redact != "" {
+		redacted = strings.Split(*redact, ",")
+	}
+
+	rec := newRecorder(target, f, redacted, stderr)
+	server := &http.Server{Addr: *listen, Handler: rec}
+
+	// shut the server down gracefully on ctrl+c (so that the in-flight requests are recorded)
+	// the requests still in flight at the end of the grace period are dropped
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	shutdown := make(chan error, 1)
+	go func() {
+		<-interrupt
+		ctx, cancel := context.WithTimeout(context.Background(), *grace)
+		defer cancel()
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	fmt.Fprintf(stdout, "Recording requests to %q in %q (listening on %s, press ctrl+c to stop)\n", target, *out, *listen)
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// ListenAndServe returns right away on shutdown (wait for the in-flight requests)
+	if err := <-shutdown; err != nil {
+		fmt.Fprintf(stderr, "Dropped the requests still in flight after %s: %v\n", *grace, err)
+		server.Close()
+	}
+
+	fmt.Fprintf(stdout, "Recorded %d requests\n", rec.count())
+	return nil
+}
+
+// recorder is an [http.Handler] that forwards the requests to a target
+// and records them as JSON lines.
+type recorder struct {
+	proxy  *httputil.ReverseProxy
+	redact []string  // headers whose values are replaced by "[REDACTED]" in the recording
+	stderr io.Writer // reports the requests that couldn't be recorded
+
+	mu  sync.Mutex // guards the encoder (the requests are served concurrently)
+	enc *json.Encoder
+	n   int // number of recorded requests
+}
+
+func newRecorder(target *url.URL, w io.Writer, redact []string, stderr io.Writer) *recorder {
+	return &recorder{
+		proxy:  httputil.NewSingleHostReverseProxy(target),
+		redact: redact,
+		stderr: stderr,
+		enc:    json.NewEncoder(w),
+	}
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+
+	// read the body to record it (and give the proxy a copy to forward)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := req.Header.Clone()
+	for _, h := range hopHeaders {
+		header.Del(h)
+	}
+	// (the request is forwarded with its credentials, only the recording is redacted)
+	for _, h := range rec.redact {
+		if header.Get(h) != "" {
+			header.Set(h, "[REDACTED]")
+		}
+	}
+
+	// capture the response metadata
+	cw := &countingWriter{ResponseWriter: w, status: http.StatusOK}
+	rec.proxy.ServeHTTP(cw, req)
+
+	entry := hit.LogEntry{
+		Time:   start,
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Header: header,
+	}
+
+	// a binary body is recorded in base64
+	// (a JSON string would replace its invalid UTF-8 with U+FFFD)
+	if utf8.Valid(body) {
+		entry.Body = string(body)
+	} else {
+		entry.RawBody = body
+	}
+
+	rec.write(record{
+		LogEntry: entry,
+		Response: response{
+			Status:   cw.status,
+			Bytes:    cw.bytes,
+			Duration: time.Since(start),
+		},
+	})
+}
+
+func (rec *recorder) write(r record) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	// encode writes each record as a single line
+	if err := rec.enc.Encode(r); err != nil {
+		fmt.Fprintf(rec.stderr, "Error while recording %s %s: %v\n", r.Method, r.Path, err)
+		return
+	}
+	rec.n++
+}
+
+func (rec *recorder) count() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.n
+}
+
+// countingWriter is an [http.ResponseWriter] that captures the status code
+// and counts the bytes of the response body.
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter (e.g. to hijack the connection of a websocket
+// with an [http.ResponseController]).
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush lets the proxy flush streamed responses (e.g. server-sent events).
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
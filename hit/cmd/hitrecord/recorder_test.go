@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/faizan2786/gobyexample/hit"
+)
+
+// test that the proxied requests are recorded in a replay file that hit can read
+func TestRecorder(t *testing.T) {
+
+	// a backend service that echoes the request body
+	var gotKey string // the api key received by the backend
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotKey = req.Header.Get("X-Api-Key")
+		w.WriteHeader(http.StatusCreated)
+		io.Copy(w, req.Body)
+	}))
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL)
+
+	var replay bytes.Buffer
+	proxy := httptest.NewServer(newRecorder(target, &replay, hit.DefaultRedact, io.Discard))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/items?source=test", strings.NewReader(`{"id": 1}`))
+	req.Header.Set("X-Api-Key", "secret")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("proxy request = %v; want no error\n", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	// the request is forwarded to the backend
+	if res.StatusCode != http.StatusCreated || string(body) != `{"id": 1}` {
+		t.Fatalf("proxy response: got = %d %s, want = %d %s\n", res.StatusCode, body, http.StatusCreated, `{"id": 1}`)
+	}
+
+	// the recording is a valid replay file
+	var entries []hit.LogEntry
+	for e, err := range hit.ReadAccessLog(bytes.NewReader(replay.Bytes()), hit.LogJSONL) {
+		if err != nil {
+			t.Fatalf("ReadAccessLog() = %v; want no error\n", err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d recorded requests; want 1\n", len(entries))
+	}
+
+	e := entries[0]
+	if e.Method != http.MethodPost || e.Path != "/items?source=test" || e.Body != `{"id": 1}` {
+		t.Errorf("recorded request: got = %s %s %s, want = POST /items?source=test {\"id\": 1}\n", e.Method, e.Path, e.Body)
+	}
+	// the credentials are forwarded but redacted from the recording
+	if gotKey != "secret" {
+		t.Errorf("forwarded X-Api-Key header: got = %q, want = %q\n", gotKey, "secret")
+	}
+	if got := e.Header.Get("X-Api-Key"); got != "[REDACTED]" {
+		t.Errorf("recorded X-Api-Key header: got = %q, want = %q\n", got, "[REDACTED]")
+	}
+
+	// the response metadata is recorded as well
+	var r record
+	if err := json.Unmarshal(replay.Bytes(), &r); err != nil {
+		t.Fatalf("json.Unmarshal() = %v; want no error\n", err)
+	}
+	if r.Response.Status != http.StatusCreated || r.Response.Bytes != int64(len(`{"id": 1}`)) {
+		t.Errorf("recorded response: got = %+v, want status %d and %d bytes\n", r.Response, http.StatusCreated, len(`{"id": 1}`))
+	}
+}
+
+// test that a binary body is recorded (and replayed) unchanged
+func TestRecorderBinaryBody(t *testing.T) {
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL)
+
+	var replay bytes.Buffer
+	proxy := httptest.NewServer(newRecorder(target, &replay, hit.DefaultRedact, io.Discard))
+	defer proxy.Close()
+
+	want := []byte{0xff, 0x00, 0x80, 'h', 'i', 0xfe}
+	res, err := http.Post(proxy.URL+"/upload", "application/octet-stream", bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("proxy request = %v; want no error\n", err)
+	}
+	res.Body.Close()
+
+	for e, err := range hit.ReadAccessLog(bytes.NewReader(replay.Bytes()), hit.LogJSONL) {
+		if err != nil {
+			t.Fatalf("ReadAccessLog() = %v; want no error\n", err)
+		}
+		if !bytes.Equal(e.RawBody, want) || e.Body != "" {
+			t.Errorf("recorded body: got = %q (raw %v), want raw %v\n", e.Body, e.RawBody, want)
+		}
+	}
+}
+
+// test that an upgraded connection (e.g. a websocket) is proxied
+// (the proxy hijacks the connection through the recorder's ResponseWriter)
+func TestRecorderUpgrade(t *testing.T) {
+
+	// a backend service that echoes the bytes sent after the upgrade
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		rw.Flush()
+		io.Copy(conn, rw)
+	}))
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL)
+
+	var replay bytes.Buffer
+	proxy := httptest.NewServer(newRecorder(target, &replay, nil, io.Discard))
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxy.URL, "http://"))
+	if err != nil {
+		t.Fatalf("net.Dial() = %v; want no error\n", err)
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, "GET /echo HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("http.ReadResponse() = %v; want no error\n", err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status: got = %d, want = %d\n", res.StatusCode, http.StatusSwitchingProtocols)
+	}
+
+	io.WriteString(conn, "ping")
+	got := make([]byte, 4)
+	if _, err := io.ReadFull(br, got); err != nil || string(got) != "ping" {
+		t.Errorf("echo: got = %q (%v), want = %q\n", got, err, "ping")
+	}
+}
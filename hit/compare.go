@@ -0,0 +1,357 @@
+// This file defines the comparison of two runs (e.g. before and after a deploy)
+// with statistical tests, so that a difference is only reported when it's unlikely to be noise:
+// the latencies are compared with a Mann-Whitney U test (a shift of the whole distribution)
+// and with the confidence intervals of their percentiles (a change of the tail only),
+// and the error rates with a two-proportion z-test.
+// As all the targets are tested at once, the tests are corrected for the multiple comparisons
+// (otherwise, the more targets, the more likely one of them differs by chance)
+
+package hit
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// CompareOptions defines the options of [Compare].
+type CompareOptions struct {
+	// significance level of the tests (a difference is significant if its p-value is lower)
+	// Default: 0.05
+	Alpha float64
+
+	// confidence level of the confidence intervals of the medians
+	// Default: 0.95
+	Confidence float64
+
+	// minimum relative increase of the latency (at one of the compared percentiles) counted as a regression (e.g. 0.05 for 5%)
+	// a large run detects tiny (significant) differences that may not matter
+	// Default: 0 (any significant increase)
+	MinDelta float64
+}
+
+// Comparison is the comparison of the results of two runs (of all their targets or of one of them).
+type Comparison struct {
+	Target string // Target is the name of the compared target ("" for all the targets)
+
+	Base, New Sample // Base and New describe the results of each run
+
+	// Percentiles are the deltas of the usual percentiles (p50, p90, p95 and p99) of the latency
+	Percentiles []PercentileDelta
+
+	MedianDelta float64 // MedianDelta is the relative change of the median latency (e.g. 0.1 for +10%)
+
+	// PSlower is the probability that a new latency is higher than a base latency (the ties count half)
+	// i.e. 0.5 if neither run is faster (it's the direction of the U test)
+	PSlower float64
+
+	// P is the p-value of the Mann-Whitney U test of the latencies (1 if there are too few samples)
+	// and ErrorP is the p-value of the two-proportion z-test of the error rates.
+	// Both are adjusted for the multiple comparisons of [Compare] (Holm-Bonferroni method).
+	P, ErrorP float64
+
+	// Slower is true if the new latency is significantly higher by at least MinDelta
+	// i.e. the U test is significant towards the new run (with one of the percentiles higher by MinDelta)
+	// or one of the percentiles is significantly higher by MinDelta (e.g. p99 doubles but p50 doesn't change)
+	Slower    bool
+	Faster    bool // Faster is true if the new latency is significantly lower (and no percentile is higher)
+	MoreError bool // MoreError is true if the new error rate is significantly higher
+}
+
+// Regression reports whether the new run is significantly worse than the base run.
+func (c Comparison) Regression() bool {
+	return c.Slower || c.MoreError
+}
+
+// Sample describes the (measured) results of a run in a [Comparison].
+// The latencies are the durations of the successful requests
+// (e.g. a failed connection is fast but isn't a faster response).
+type Sample struct {
+	Requests  int     // Requests is the number of requests
+	Errors    int     // Errors is the number of failed requests
+	ErrorRate float64 // ErrorRate is the ratio of failed requests
+
+	Median                time.Duration // Median is the median latency
+	MedianLow, MedianHigh time.Duration // MedianLow and MedianHigh are the bounds of the confidence interval of the median
+
+	durations []time.Duration // sorted latencies
+}
+
+// PercentileDelta is the change of a percentile of the latency.
+type PercentileDelta struct {
+	P         float64       // P is the percentile (e.g. 99)
+	Base, New time.Duration // Base and New are the percentile in each run
+	Delta     float64       // Delta is the relative change (e.g. 0.1 for +10%)
+
+	// Higher is true if the new percentile is significantly higher
+	// (i.e. the confidence intervals of the percentile in each run don't overlap)
+	Higher bool
+}
+
+// minSamples is the minimum number of latencies of each run to test their difference
+// (the normal approximation of the U test needs a few samples)
+const minSamples = 8
+
+// comparedPercentiles are the percentiles of a [Comparison]
+var comparedPercentiles = []float64{50, 90, 95, 99}
+
+// Compare compares the results of a base run with the results of a new run,
+// first for all of their targets and then for each named target of both runs (by name).
+// The tests of all the comparisons are corrected for the multiple comparisons (i.e. their family-wise error rate is Alpha):
+// the p-values are adjusted with the Holm-Bonferroni method
+// and the confidence intervals of the percentiles are widened with the Bonferroni method.
+func Compare(base, candidate []Result, opts CompareOptions) []Comparison {
+	if opts.Alpha <= 0 {
+		opts.Alpha = 0.05
+	}
+	if opts.Confidence <= 0 || opts.Confidence >= 1 {
+		opts.Confidence = 0.95
+	}
+
+	byTarget := func(results []Result) map[string][]Result {
+		m := map[string][]Result{}
+		for _, r := range results {
+			if r.Target != "" {
+				m[r.Target] = append(m[r.Target], r)
+			}
+		}
+		return m
+	}
+
+	comparisons := []Comparison{compare("", base, candidate, opts)}
+
+	baseTargets, newTargets := byTarget(base), byTarget(candidate)
+	for _, name := range slices.Sorted(maps.Keys(baseTargets)) {
+		if _, ok := newTargets[name]; ok {
+			comparisons = append(comparisons, compare(name, baseTargets[name], newTargets[name], opts))
+		}
+	}
+
+	// adjust the p-values of the tests that were run (a U test needs a few samples)
+	var ps []*float64
+	for i := range comparisons {
+		c := &comparisons[i]
+		if len(c.Base.durations) >= minSamples && len(c.New.durations) >= minSamples {
+			ps = append(ps, &c.P)
+		}
+		ps = append(ps, &c.ErrorP)
+	}
+	holm(ps)
+
+	// each percentile is tested at Alpha divided by the number of tested percentiles
+	confidence := 1 - opts.Alpha/float64(len(comparisons)*len(comparedPercentiles))
+	for i := range comparisons {
+		comparisons[i].verdict(opts, confidence)
+	}
+
+	return comparisons
+}
+
+// compare compares the results of a target (or all of them) of two runs
+// (the verdict is left to [Comparison.verdict], once the p-values of all the comparisons are adjusted).
+func compare(target string, base, candidate []Result, opts CompareOptions) Comparison {
+	c := Comparison{
+		Target:  target,
+		Base:    newSample(base, opts.Confidence),
+		New:     newSample(candidate, opts.Confidence),
+		PSlower: 0.5,
+		P:       1,
+		ErrorP:  1,
+	}
+
+	b, n := c.Base.durations, c.New.durations
+	if len(b) > 0 && len(n) > 0 {
+		for _, p := range comparedPercentiles {
+			pb, pn := percentile(b, p), percentile(n, p)
+			c.Percentiles = append(c.Percentiles, PercentileDelta{P: p, Base: pb, New: pn, Delta: relative(pb, pn)})
+		}
+		c.MedianDelta = relative(c.Base.Median, c.New.Median)
+	}
+
+	if len(b) >= minSamples && len(n) >= minSamples {
+		c.P, c.PSlower = mannWhitney(b, n)
+	}
+
+	c.ErrorP = twoProportions(c.Base.Errors, c.Base.Requests, c.New.Errors, c.New.Requests)
+
+	return c
+}
+
+// verdict flags the significant differences of a comparison
+// (the percentiles are tested with confidence intervals at the given confidence level).
+func (c *Comparison) verdict(opts CompareOptions, confidence float64) {
+	b, n := c.Base.durations, c.New.durations
+
+	// the largest relative increase of a percentile
+	// and whether a percentile is significantly higher (by at least MinDelta)
+	var (
+		maxDelta float64
+		higher   bool
+	)
+	for i := range c.Percentiles {
+		d := &c.Percentiles[i]
+		if len(b) >= minSamples && len(n) >= minSamples {
+			_, baseHigh := percentileInterval(b, d.P, confidence)
+			newLow, _ := percentileInterval(n, d.P, confidence)
+			d.Higher = newLow > baseHigh
+		}
+		maxDelta = max(maxDelta, d.Delta)
+		higher = higher || (d.Higher && d.Delta > opts.MinDelta)
+	}
+
+	significant := c.P < opts.Alpha
+	c.Slower = (significant && c.PSlower > 0.5 && maxDelta > opts.MinDelta) || higher
+	c.Faster = significant && c.PSlower < 0.5 && !c.Slower
+
+	c.MoreError = c.ErrorP < opts.Alpha && c.New.ErrorRate > c.Base.ErrorRate
+}
+
+// newSample describes the measured results of a run
+// (with a confidence interval of the median at the given confidence level).
+func newSample(results []Result, confidence float64) Sample {
+	var s Sample
+
+	for _, r := range results {
+		// the results left out of a summary are left out of the comparison
+		if r.Skipped || r.Abandoned || r.Warmup || isAuthError(r.Error) {
+			continue
+		}
+		s.Requests++
+		if r.Error != nil {
+			s.Errors++
+			continue
+		}
+		s.durations = append(s.durations, r.Duration)
+	}
+
+	if s.Requests > 0 {
+		s.ErrorRate = float64(s.Errors) / float64(s.Requests)
+	}
+
+	n := len(s.durations)
+	if n == 0 {
+		return s
+	}
+	slices.Sort(s.durations)
+	s.Median = percentile(s.durations, 50)
+	s.MedianLow, s.MedianHigh = percentileInterval(s.durations, 50, confidence)
+
+	return s
+}
+
+// percentileInterval returns the confidence interval of the p-th percentile of sorted durations.
+func percentileInterval(sorted []time.Duration, p, confidence float64) (lo, hi time.Duration) {
+	n := float64(len(sorted))
+	q := p / 100
+
+	// the bounds of the interval are order statistics around the percentile
+	// (the number of values below the percentile is binomial, approximated by a normal distribution)
+	// i.e. the values of rank n*q ± z*sqrt(n*q*(1-q))
+	z := math.Sqrt2 * math.Erfinv(confidence)
+	half := z * math.Sqrt(n*q*(1-q))
+	low := int(math.Floor(n*q - half))   // (rank)
+	high := int(math.Ceil(n*q+half)) + 1 // (rank)
+	return sorted[min(max(low, 1), len(sorted))-1], sorted[min(max(high, 1), len(sorted))-1]
+}
+
+// percentile returns the p-th percentile (nearest rank) of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p/100*float64(len(sorted)) - 1e-9))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// relative returns the relative change from base to v (0 if base is 0).
+func relative(base, v time.Duration) float64 {
+	if base == 0 {
+		return 0
+	}
+	return float64(v-base) / float64(base)
+}
+
+// mannWhitney returns the (two-sided) p-value of the Mann-Whitney U test of two sorted samples,
+// i.e. the probability that the samples differ as much (or more) if they come from the same distribution,
+// and the probability that a value of y is higher than a value of x (the ties count half), i.e. its direction.
+// It uses the normal approximation of U (with a tie and a continuity correction).
+func mannWhitney(x, y []time.Duration) (p, pHigher float64) {
+	n1, n2 := float64(len(x)), float64(len(y))
+	n := n1 + n2
+
+	// rank the merged samples (the tied values share the average of their ranks)
+	// and sum the ranks of x
+	var (
+		rankSum, ties float64
+		i, j          int
+		rank          float64 // number of values ranked so far
+	)
+	for i < len(x) || j < len(y) {
+		// the next (lowest) value of the merged samples
+		var v time.Duration
+		if j == len(y) || (i < len(x) && x[i] <= y[j]) {
+			v = x[i]
+		} else {
+			v = y[j]
+		}
+
+		var cx, cy float64 // number of values equal to v in each sample
+		for ; i < len(x) && x[i] == v; i++ {
+			cx++
+		}
+		for ; j < len(y) && y[j] == v; j++ {
+			cy++
+		}
+
+		t := cx + cy
+		rankSum += cx * (rank + (t+1)/2)
+		ties += t*t*t - t
+		rank += t
+	}
+
+	// u is the number of pairs where the value of x is higher (the ties count half)
+	u := rankSum - n1*(n1+1)/2
+	pHigher = 1 - u/(n1*n2)
+
+	mean := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1, pHigher // all the values are equal
+	}
+
+	z := max(math.Abs(u-mean)-0.5, 0) / sigma
+	return math.Erfc(z / math.Sqrt2), pHigher
+}
+
+// holm adjusts the p-values of a family of tests for the multiple comparisons (Holm-Bonferroni method):
+// the i-th lowest of m p-values is multiplied by m-i (from i=0), and kept at least as high as the lower ones.
+// (a test is then significant if its adjusted p-value is lower than alpha)
+func holm(ps []*float64) {
+	sorted := slices.SortedStableFunc(slices.Values(ps), func(a, b *float64) int {
+		return cmp.Compare(*a, *b)
+	})
+
+	m := len(sorted)
+	adjusted := 0.0
+	for i, p := range sorted {
+		adjusted = max(adjusted, min(1, *p*float64(m-i)))
+		*p = adjusted
+	}
+}
+
+// twoProportions returns the (two-sided) p-value of the two-proportion z-test
+// of the rates k1/n1 and k2/n2 (1 if they can't be told apart, e.g. no errors).
+func twoProportions(k1, n1, k2, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	p1, p2 := float64(k1)/float64(n1), float64(k2)/float64(n2)
+	pooled := float64(k1+k2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 1
+	}
+
+	z := math.Abs(p2-p1) / se
+	return math.Erfc(z / math.Sqrt2)
+}
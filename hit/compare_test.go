@@ -0,0 +1,179 @@
+package hit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// sampleResults returns n results of the given target with exponential durations (plus a base latency)
+// and the given ratio of errors
+func sampleResults(rnd *rand.Rand, n int, target string, base, mean time.Duration, errorRate float64) []Result {
+	results := make([]Result, n)
+	for i := range results {
+		results[i] = Result{Target: target, Duration: base + time.Duration(rnd.ExpFloat64()*float64(mean))}
+		if rnd.Float64() < errorRate {
+			results[i].Error = errors.New("timeout")
+		}
+	}
+	return results
+}
+
+// slowTail multiplies the durations of the slowest results (above the p-th percentile) by factor
+// (i.e. the tail is slower but the median doesn't change)
+func slowTail(results []Result, p float64, factor time.Duration) []Result {
+	durations := make([]time.Duration, len(results))
+	for i, r := range results {
+		durations[i] = r.Duration
+	}
+	slices.Sort(durations)
+	threshold := percentile(durations, p)
+
+	for i := range results {
+		if results[i].Duration > threshold {
+			results[i].Duration *= factor
+		}
+	}
+	return results
+}
+
+func TestCompare(t *testing.T) {
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+	ms := time.Millisecond
+
+	base := append(sampleResults(rnd, 500, "items", 10*ms, 5*ms, 0.01), sampleResults(rnd, 500, "users", 20*ms, 5*ms, 0.01)...)
+
+	testCases := []struct {
+		name      string
+		candidate []Result
+		opts      CompareOptions
+
+		slower, faster, moreError bool
+	}{
+		{
+			name:      "same",
+			candidate: append(sampleResults(rnd, 500, "items", 10*ms, 5*ms, 0.01), sampleResults(rnd, 500, "users", 20*ms, 5*ms, 0.01)...),
+		},
+		{
+			name:      "slower",
+			candidate: append(sampleResults(rnd, 500, "items", 13*ms, 5*ms, 0.01), sampleResults(rnd, 500, "users", 26*ms, 5*ms, 0.01)...),
+			slower:    true,
+		},
+		{
+			name:      "slower_below_min_delta",
+			candidate: append(sampleResults(rnd, 500, "items", 13*ms, 5*ms, 0.01), sampleResults(rnd, 500, "users", 26*ms, 5*ms, 0.01)...),
+			opts:      CompareOptions{MinDelta: 0.5},
+		},
+		{
+			// the U test alone misses it, the percentiles don't
+			name:      "slower_tail",
+			candidate: append(slowTail(sampleResults(rnd, 500, "items", 10*ms, 5*ms, 0.01), 80, 3), slowTail(sampleResults(rnd, 500, "users", 20*ms, 5*ms, 0.01), 80, 3)...),
+			slower:    true,
+		},
+		{
+			name:      "faster",
+			candidate: append(sampleResults(rnd, 500, "items", 7*ms, 5*ms, 0.01), sampleResults(rnd, 500, "users", 14*ms, 5*ms, 0.01)...),
+			faster:    true,
+		},
+		{
+			name:      "more_errors",
+			candidate: append(sampleResults(rnd, 500, "items", 10*ms, 5*ms, 0.1), sampleResults(rnd, 500, "users", 20*ms, 5*ms, 0.1)...),
+			moreError: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(base, tt.candidate, tt.opts)
+
+			// all the targets and then each target
+			if len(got) != 3 || got[0].Target != "" || got[1].Target != "items" || got[2].Target != "users" {
+				t.Fatalf("Compare(): got %d comparisons, want all the targets, items and users\n", len(got))
+			}
+
+			for _, c := range got {
+				if c.Slower != tt.slower || c.Faster != tt.faster || c.MoreError != tt.moreError {
+					t.Errorf("%q: got = slower %v, faster %v, more errors %v (p=%.4f, error p=%.4f, median %+.1f%%), want = %v, %v, %v\n",
+						c.Target, c.Slower, c.Faster, c.MoreError, c.P, c.ErrorP, c.MedianDelta*100, tt.slower, tt.faster, tt.moreError)
+				}
+				if c.Regression() != (tt.slower || tt.moreError) {
+					t.Errorf("%q Regression(): got = %v, want = %v\n", c.Target, c.Regression(), tt.slower || tt.moreError)
+				}
+			}
+
+			all := got[0]
+			if all.Base.Requests != 1000 || all.New.Requests != 1000 {
+				t.Errorf("Requests: got = %d and %d, want = 1000 and 1000\n", all.Base.Requests, all.New.Requests)
+			}
+			if all.Base.MedianLow > all.Base.Median || all.Base.Median > all.Base.MedianHigh {
+				t.Errorf("median: got = %v not in its interval [%v, %v]\n", all.Base.Median, all.Base.MedianLow, all.Base.MedianHigh)
+			}
+			if len(all.Percentiles) != 4 || all.Percentiles[0].Base != all.Base.Median {
+				t.Errorf("Percentiles: got = %+v, want p50 (the median), p90, p95 and p99\n", all.Percentiles)
+			}
+		})
+	}
+}
+
+// test the p-value of the U test against a known value
+// (R: wilcox.test(1:10, 11:20, exact = FALSE, correct = TRUE) gives 0.0001817)
+func TestMannWhitney(t *testing.T) {
+
+	var x, y []time.Duration
+	for i := range 10 {
+		x = append(x, time.Duration(i+1))
+		y = append(y, time.Duration(i+11))
+	}
+
+	// every value of y is higher
+	if p, higher := mannWhitney(x, y); math.Abs(p-0.0001817) > 1e-6 || higher != 1 {
+		t.Errorf("mannWhitney(): got = %.7f, %v, want = %.7f, %v\n", p, higher, 0.0001817, 1)
+	}
+
+	// identical samples (all ties)
+	if p, higher := mannWhitney(x, x); p != 1 || higher != 0.5 {
+		t.Errorf("mannWhitney() of identical samples: got = %v, %v, want = %v, %v\n", p, higher, 1, 0.5)
+	}
+}
+
+// test that many targets that don't differ aren't flagged by chance
+// (each of them is tested at alpha without a correction for the multiple comparisons)
+func TestCompareManyTargets(t *testing.T) {
+
+	rnd := rand.New(rand.NewPCG(3, 4))
+	ms := time.Millisecond
+
+	var base, candidate []Result
+	for i := range 50 {
+		name := fmt.Sprintf("target-%d", i)
+		base = append(base, sampleResults(rnd, 200, name, 10*ms, 5*ms, 0.05)...)
+		candidate = append(candidate, sampleResults(rnd, 200, name, 10*ms, 5*ms, 0.05)...)
+	}
+
+	for _, c := range Compare(base, candidate, CompareOptions{}) {
+		if c.Regression() {
+			t.Errorf("%q: got a regression (p=%.4f, error p=%.4f), want none\n", c.Target, c.P, c.ErrorP)
+		}
+	}
+}
+
+// test the Holm-Bonferroni adjustment against known values
+// (R: p.adjust(c(0.01, 0.04, 0.03, 0.005), "holm") gives 0.03 0.06 0.06 0.02)
+func TestHolm(t *testing.T) {
+
+	ps := []float64{0.01, 0.04, 0.03, 0.005}
+	holm([]*float64{&ps[0], &ps[1], &ps[2], &ps[3]})
+
+	want := []float64{0.03, 0.06, 0.06, 0.02}
+	for i := range ps {
+		if math.Abs(ps[i]-want[i]) > 1e-12 {
+			t.Errorf("holm(): got = %v, want = %v\n", ps, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package hit
+
+import "time"
+
+// cpuTime reports that the CPU time of the process is unknown on this platform
+// (the CPU usage of the client isn't monitored).
+func cpuTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,243 @@
+// This file defines the debug dumps of the request/response exchanges of a run
+// (e.g. to see why a target returns unexpected 400s without reproducing the requests by hand)
+
+package hit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRedact are the headers redacted from the dumps by default (see [DumpOptions])
+var DefaultRedact = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// DumpOptions defines which exchanges [Dump] writes and how.
+type DumpOptions struct {
+	// number of first exchanges dumped
+	First int
+
+	// also dump every failing exchange (an error or a status code of 400 or above)
+	Failed bool
+
+	// maximum number of bytes of a request or response body in a dump (the rest is cut)
+	// Default: 1024 (negative to leave out the bodies)
+	MaxBody int
+
+	// headers whose values are replaced by "[REDACTED]" in a dump (e.g. credentials)
+	// Default: [DefaultRedact]
+	Redact []string
+}
+
+// Dump writes the full exchanges (request and response, with their headers and bodies) to w, e.g.
+//
+//	=== exchange 1: GET http://localhost:8082/items -> 400 (12ms)
+//	GET /items HTTP/1.1
+//	Host: localhost:8082
+//	Authorization: [REDACTED]
+//	...
+//
+//	HTTP/1.1 400 Bad Request
+//	Content-Type: application/json
+//	...
+//
+//	{"error": "missing id"}
+//
+// The requests are dumped as they're sent (see [httputil.DumpRequestOut]).
+// The exchanges of concurrent requests are not interleaved.
+// Only the cheap state of a request (its url, headers and body) is recorded before it's sent,
+// the dump itself is only built for the exchanges that are dumped.
+//
+// It must be the innermost middleware (i.e. the last one) to see the request as it's sent,
+// and it only captures the response body of the default Send function (see [Options]).
+func Dump(w io.Writer, opts DumpOptions) Middleware {
+	if opts.MaxBody == 0 {
+		opts.MaxBody = 1024
+	}
+	if opts.Redact == nil {
+		opts.Redact = DefaultRedact
+	}
+
+	var (
+		count atomic.Int64 // number of exchanges so far
+		mu    sync.Mutex   // guards w
+	)
+
+	return func(next SendFunc) SendFunc {
+		return func(req *http.Request) Result {
+			n := count.Add(1)
+			first := n <= int64(opts.First)
+			if !first && !opts.Failed {
+				return next(req) // nothing to dump
+			}
+
+			// the request is recorded before it's sent (its body can only be read once)
+			// but only dumped once we know the exchange is
+			rec := recordRequest(req, opts)
+
+			c := &capture{max: opts.MaxBody, all: first}
+			r := next(req.WithContext(context.WithValue(req.Context(), captureKey{}, c)))
+
+			if !first && r.Error == nil && r.Status < http.StatusBadRequest {
+				return r
+			}
+
+			dump := &bytes.Buffer{}
+			dumpRequest(dump, rec, opts)
+			if r.Error != nil {
+				fmt.Fprintf(dump, "\nerror: %v\n", r.Error)
+			} else if c.res != nil {
+				dumpResponse(dump, c, r.Bytes, opts)
+			}
+
+			status := "error"
+			if r.Status != 0 {
+				status = fmt.Sprint(r.Status)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(w, "=== exchange %d: %s %s -> %s (%s)\n%s\n", n, req.Method, req.URL, status, r.Duration.Round(time.Millisecond), dump)
+
+			return r
+		}
+	}
+}
+
+// captureKey is the context key of the capture of a request (see [Dump]).
+type captureKey struct{}
+
+// capture holds the response of a request sent by [Send] (and the start of its body)
+// Send looks it up in the request context, much like an [net/http/httptrace.ClientTrace].
+type capture struct {
+	max  int            // maximum number of bytes of the body kept
+	all  bool           // keep the body of any response (not only of the failed ones)
+	res  *http.Response // the response (its body is read by Send)
+	body bytes.Buffer   // the start of the response body
+}
+
+// captureResponse captures res (and the start of its body) if the request asks for it (see [Dump]).
+func captureResponse(req *http.Request, res *http.Response) {
+	c, ok := req.Context().Value(captureKey{}).(*capture)
+	if !ok {
+		return
+	}
+
+	c.res = res
+	if c.max > 0 && (c.all || res.StatusCode >= http.StatusBadRequest) {
+		// keep the start of the body as Send reads it
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(res.Body, limitWriter{&c.body, c.max}), res.Body}
+	}
+}
+
+// limitWriter writes up to n bytes to w and discards the rest (without failing).
+type limitWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+func (lw limitWriter) Write(p []byte) (int, error) {
+	if left := lw.n - lw.w.Len(); left > 0 {
+		lw.w.Write(p[:min(len(p), left)])
+	}
+	return len(p), nil
+}
+
+// recordRequest records the state of req before it's sent, i.e. a copy with its own url and headers
+// whose body can be read again with GetBody (the body is only read up front if req has no GetBody).
+func recordRequest(req *http.Request, opts DumpOptions) *http.Request {
+	rec := *req
+	u := *req.URL
+	rec.URL = &u
+	rec.Header = req.Header.Clone()
+
+	if req.GetBody == nil && opts.MaxBody >= 0 {
+		body, err := requestBody(req) // (read and put back)
+		rec.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), err
+		}
+	}
+	return &rec
+}
+
+// dumpRequest writes a recorded request (with its headers redacted and its body cut) to w
+// (see [recordRequest]).
+func dumpRequest(w *bytes.Buffer, req *http.Request, opts DumpOptions) {
+	redact(req.Header, opts.Redact)
+
+	// the body is written separately (to cut it)
+	head, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		fmt.Fprintf(w, "%s %s (can't dump the request: %v)\n", req.Method, req.URL, err)
+		return
+	}
+	w.Write(head)
+
+	if opts.MaxBody < 0 {
+		return
+	}
+	body, err := requestBody(req)
+	if err != nil {
+		fmt.Fprintf(w, "(can't read the request body: %v)\n", err)
+		return
+	}
+	writeBody(w, body, int64(len(body)), opts.MaxBody)
+}
+
+// dumpResponse writes the captured response (with its headers redacted and its body cut) to w
+// (size is the size of the response body).
+func dumpResponse(w *bytes.Buffer, c *capture, size int64, opts DumpOptions) {
+	// (a copy without a body, the body was already read by Send)
+	dres := *c.res
+	dres.Header = c.res.Header.Clone()
+	dres.Body = http.NoBody
+	redact(dres.Header, opts.Redact)
+
+	head, err := httputil.DumpResponse(&dres, false)
+	if err != nil {
+		fmt.Fprintf(w, "\n(can't dump the response: %v)\n", err)
+		return
+	}
+	w.WriteString("\n")
+	w.Write(head)
+
+	if opts.MaxBody < 0 {
+		return
+	}
+	writeBody(w, c.body.Bytes(), size, opts.MaxBody)
+}
+
+// writeBody writes the start of a body (of the given size) to w
+// and notes how many bytes were cut.
+func writeBody(w *bytes.Buffer, body []byte, size int64, maxBody int) {
+	if size == 0 {
+		return
+	}
+
+	body = body[:min(len(body), maxBody)]
+	w.Write(body)
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		w.WriteString("\n")
+	}
+	if cut := size - int64(len(body)); cut > 0 {
+		fmt.Fprintf(w, "... (%d more bytes)\n", cut)
+	}
+}
+
+// redact replaces the values of the given headers (if set).
+func redact(header http.Header, names []string) {
+	for _, name := range names {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			header.Set(name, "[REDACTED]")
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package hit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// test that the first exchanges and the failing ones are dumped
+// with their headers redacted and their bodies cut
+func TestDump(t *testing.T) {
+
+	// a server that rejects the requests without an id
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if !strings.Contains(string(body), "id") {
+			w.Header().Set("Set-Cookie", "session=secret")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error": "missing id, `+strings.Repeat("x", 100)+`"}`)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	send := Chain(
+		func(req *http.Request) Result { return Send(server.Client(), req) },
+		Dump(&out, DumpOptions{First: 1, Failed: true, MaxBody: 16}),
+	)
+
+	post := func(body io.Reader) Result {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/items", body)
+		req.Header.Set("Authorization", "Bearer secret")
+		return send(req)
+	}
+
+	post(strings.NewReader(`{"id": 1}`))          // dumped (first)
+	post(strings.NewReader(`{"id": 2}`))          // not dumped
+	r := post(strings.NewReader(`{"name": "x"}`)) // dumped (failed)
+
+	// a body that can't be read again (i.e. without GetBody) is still sent and dumped
+	post(io.MultiReader(strings.NewReader(`{"name": "y"}`)))
+
+	// the body is still counted by Send
+	if want := int64(len(`{"error": "missing id, `) + 100 + len(`"}`)); r.Bytes != want {
+		t.Errorf("Bytes: got = %d, want = %d\n", r.Bytes, want)
+	}
+
+	got := out.String()
+
+	for _, want := range []string{
+		"=== exchange 1: POST " + server.URL + "/items -> 200",
+		"=== exchange 3: POST " + server.URL + "/items -> 400",
+		"=== exchange 4: POST " + server.URL + "/items -> 400",
+		"POST /items HTTP/1.1",
+		"Authorization: [REDACTED]",
+		"Set-Cookie: [REDACTED]",
+		"HTTP/1.1 400 Bad Request",
+		`{"name": "x"}`, // the request body
+		`{"name": "y"}`,
+		`{"error": "missi` + "\n... (109 more bytes)", // the response body is cut
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dump: got = %s, want it to contain %q\n", got, want)
+		}
+	}
+
+	for _, unwanted := range []string{"exchange 2", "secret"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("dump: got = %s, want it not to contain %q\n", got, unwanted)
+		}
+	}
+}
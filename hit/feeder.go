@@ -0,0 +1,206 @@
+// This file defines a data feeder that streams records from a CSV or JSONL file
+// to the request templates (e.g. real customer IDs or search terms)
+//
+// The records are read from the file as they are needed (instead of loading the whole file into memory)
+// and each record's fields are available in the templates as {{.Data.field}}
+
+package hit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Record is a single record of a [Feeder] (a field name to value map).
+type Record map[string]any
+
+// FeedFormat is the file format of a [Feeder].
+type FeedFormat int
+
+const (
+	FeedCSV   FeedFormat = iota // CSV with a header row of field names
+	FeedJSONL                   // one JSON object per line
+)
+
+// FeedMode defines how the records of a [Feeder] are assigned to the requests.
+type FeedMode int
+
+const (
+	FeedSequential FeedMode = iota // each request gets the next record in file order
+	FeedRandom                     // each request gets a random record
+	FeedUnique                     // each worker (i.e. virtual user) gets its own record for all of its requests
+)
+
+// shuffleSize is the number of records buffered to pick a random record from
+// (so that random mode doesn't need to load the whole file into memory)
+const shuffleSize = 1000
+
+// Feeder streams [Record] values from a CSV or JSONL source.
+// It is safe for concurrent use.
+type Feeder struct {
+	Mode    FeedMode // Mode defines how the records are assigned to the requests
+	Recycle bool     // Recycle restarts from the first record when the records run out (otherwise the run stops)
+
+	mu      sync.Mutex
+	src     io.ReadSeeker
+	format  FeedFormat
+	next    func() (Record, error) // reads the next record from the source
+	shuffle []Record               // buffered records (random mode only)
+}
+
+// NewFeeder returns a [Feeder] that reads records of the given format from src.
+func NewFeeder(src io.ReadSeeker, format FeedFormat, mode FeedMode, recycle bool) (*Feeder, error) {
+	f := &Feeder{
+		Mode:    mode,
+		Recycle: recycle,
+		src:     src,
+		format:  format,
+	}
+
+	if err := f.reset(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// OpenFeeder opens a [Feeder] for the file at path.
+// The format is derived from the file extension (.csv, .jsonl or .ndjson).
+// The caller must close the returned feeder.
+func OpenFeeder(path string, mode FeedMode, recycle bool) (*Feeder, error) {
+	var format FeedFormat
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		format = FeedCSV
+	case ".jsonl", ".ndjson":
+		format = FeedJSONL
+	default:
+		return nil, fmt.Errorf("unsupported data file extension %q: want .csv, .jsonl or .ndjson", ext)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := NewFeeder(file, format, mode, recycle)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close closes the feeder's source if it is an [io.Closer].
+func (f *Feeder) Close() error {
+	if c, ok := f.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Next returns the next record.
+// It returns [io.EOF] when the records run out and the feeder doesn't recycle them.
+func (f *Feeder) Next() (Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Mode != FeedRandom {
+		return f.read()
+	}
+
+	// fill the shuffle buffer and take a random record out of it
+	for len(f.shuffle) < shuffleSize {
+		r, err := f.read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.shuffle = append(f.shuffle, r)
+	}
+
+	if len(f.shuffle) == 0 {
+		return nil, io.EOF
+	}
+
+	i := rand.IntN(len(f.shuffle))
+	r := f.shuffle[i]
+	last := len(f.shuffle) - 1
+	f.shuffle[i], f.shuffle[last] = f.shuffle[last], nil
+	f.shuffle = f.shuffle[:last]
+
+	return r, nil
+}
+
+// read reads the next record from the source (rewinding the source if the feeder recycles the records).
+func (f *Feeder) read() (Record, error) {
+	r, err := f.next()
+	if !errors.Is(err, io.EOF) || !f.Recycle {
+		return r, err
+	}
+
+	// start over from the first record
+	if err := f.reset(); err != nil {
+		return nil, err
+	}
+	r, err = f.next()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("data feeder has no records")
+	}
+	return r, err
+}
+
+// reset rewinds the source and creates a new record reader for it.
+func (f *Feeder) reset() error {
+	if _, err := f.src.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewinding data feeder: %w", err)
+	}
+
+	switch f.format {
+	case FeedCSV:
+		r := csv.NewReader(f.src)
+		fields, err := r.Read() // the header row
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading csv header: %w", err)
+		}
+		f.next = func() (Record, error) {
+			values, err := r.Read()
+			if err != nil {
+				return nil, err
+			}
+			rec := make(Record, len(fields))
+			for i, field := range fields {
+				rec[field] = values[i]
+			}
+			return rec, nil
+		}
+
+	case FeedJSONL:
+		// a json decoder reads a stream of JSON values one by one (i.e. one record per line)
+		d := json.NewDecoder(f.src)
+		f.next = func() (Record, error) {
+			var rec Record
+			if err := d.Decode(&rec); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil, err
+				}
+				return nil, fmt.Errorf("reading jsonl record: %w", err)
+			}
+			return rec, nil
+		}
+
+	default:
+		return fmt.Errorf("unknown data feeder format %d", f.format)
+	}
+
+	return nil
+}
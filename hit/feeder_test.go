@@ -0,0 +1,198 @@
+package hit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testCSV = `id,term
+1,shoes
+2,hats
+3,socks
+`
+
+const testJSONL = `{"id": 1, "term": "shoes"}
+{"id": 2, "term": "hats"}
+{"id": 3, "term": "socks"}
+`
+
+// read all the records of a feeder until it runs out (or max records are read)
+func readRecords(t *testing.T, f *Feeder, max int) []Record {
+	t.Helper()
+
+	var records []Record
+	for range max {
+		r, err := f.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() = %v; want no error\n", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestFeederSequential(t *testing.T) {
+
+	testCases := []struct {
+		name   string
+		format FeedFormat
+		data   string
+		want   []any // want ids
+	}{
+		{name: "csv", format: FeedCSV, data: testCSV, want: []any{"1", "2", "3"}},
+		{name: "jsonl", format: FeedJSONL, data: testJSONL, want: []any{1.0, 2.0, 3.0}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFeeder(strings.NewReader(tt.data), tt.format, FeedSequential, false)
+			if err != nil {
+				t.Fatalf("NewFeeder() = %v; want no error\n", err)
+			}
+
+			records := readRecords(t, f, 10)
+			if len(records) != len(tt.want) {
+				t.Fatalf("got %d records; want %d\n", len(records), len(tt.want))
+			}
+			for i, r := range records {
+				if r["id"] != tt.want[i] {
+					t.Errorf("record %d: id = %v; want %v\n", i, r["id"], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFeederRecycle(t *testing.T) {
+
+	f, err := NewFeeder(strings.NewReader(testCSV), FeedCSV, FeedSequential, true)
+	if err != nil {
+		t.Fatalf("NewFeeder() = %v; want no error\n", err)
+	}
+
+	records := readRecords(t, f, 7)
+	want := []string{"1", "2", "3", "1", "2", "3", "1"}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records; want %d\n", len(records), len(want))
+	}
+	for i, r := range records {
+		if r["id"] != want[i] {
+			t.Errorf("record %d: id = %v; want %v\n", i, r["id"], want[i])
+		}
+	}
+}
+
+// test that random mode returns each record exactly once (in some order)
+func TestFeederRandom(t *testing.T) {
+
+	f, err := NewFeeder(strings.NewReader(testJSONL), FeedJSONL, FeedRandom, false)
+	if err != nil {
+		t.Fatalf("NewFeeder() = %v; want no error\n", err)
+	}
+
+	seen := make(map[any]int)
+	for _, r := range readRecords(t, f, 10) {
+		seen[r["id"]] += 1
+	}
+	for _, id := range []any{1.0, 2.0, 3.0} {
+		if seen[id] != 1 {
+			t.Errorf("record %v returned %d times; want 1\n", id, seen[id])
+		}
+	}
+}
+
+func TestFeederInvalid(t *testing.T) {
+
+	f, err := NewFeeder(strings.NewReader("{\"id\": 1}\nnot json\n"), FeedJSONL, FeedSequential, false)
+	if err != nil {
+		t.Fatalf("NewFeeder() = %v; want no error\n", err)
+	}
+	if _, err := f.Next(); err != nil {
+		t.Fatalf("Next() = %v; want no error\n", err)
+	}
+	if _, err := f.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Next() = %v; want a decoding error\n", err)
+	}
+
+	// an empty file with recycling must not loop forever
+	f, err = NewFeeder(strings.NewReader(""), FeedCSV, FeedSequential, true)
+	if err != nil {
+		t.Fatalf("NewFeeder() = %v; want no error\n", err)
+	}
+	if _, err := f.Next(); err == nil {
+		t.Errorf("Next() error = <nil>; want an error")
+	}
+}
+
+// test that the run stops when the data runs out and the records reach the templates
+func TestSendNWithFeeder(t *testing.T) {
+
+	f, err := NewFeeder(strings.NewReader(testCSV), FeedCSV, FeedSequential, false)
+	if err != nil {
+		t.Fatalf("NewFeeder() = %v; want no error\n", err)
+	}
+
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	opts := Options{Feeder: f}
+	opts.Send = func(req *http.Request) Result {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, req.URL.Path)
+		return Result{Status: http.StatusOK}
+	}
+
+	tmpl, err := NewRequestTemplate(http.MethodGet, "http://localhost/search/{{.Data.term}}", nil, "")
+	if err != nil {
+		t.Fatalf("NewRequestTemplate() = %v; want no error\n", err)
+	}
+
+	results, err := SendTargets(context.Background(), 10, opts, Target{Template: tmpl})
+	if err != nil {
+		t.Fatalf("SendTargets() = %v; want no error\n", err)
+	}
+
+	if s := Summarize(results); s.Requests != 3 {
+		t.Errorf("Requests: got = %d, want = %d\n", s.Requests, 3)
+	}
+
+	want := "/search/shoes,/search/hats,/search/socks"
+	if got := strings.Join(paths, ","); got != want {
+		t.Errorf("paths: got = %s, want = %s\n", got, want)
+	}
+}
+
+// test that a malformed record in unique mode fails the requests of its worker
+// (rather than being mistaken for the end of the data, i.e. a run with no results)
+func TestSendNWithFeederUniqueInvalid(t *testing.T) {
+
+	f, err := NewFeeder(strings.NewReader("not json\n"), FeedJSONL, FeedUnique, false)
+	if err != nil {
+		t.Fatalf("NewFeeder() = %v; want no error\n", err)
+	}
+
+	opts := Options{Feeder: f}
+	opts.Send = func(_ *http.Request) Result {
+		return Result{Status: http.StatusOK}
+	}
+
+	results, err := SendN(context.Background(), 10, opts, getTestHttpRequest())
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+
+	s := Summarize(results)
+	if s.Requests != 1 || s.Errors != 1 {
+		t.Errorf("Requests, Errors: got = %d, %d, want = %d, %d\n", s.Requests, s.Errors, 1, 1)
+	}
+}
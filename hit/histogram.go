@@ -0,0 +1,109 @@
+// This file defines the latency histogram of a [Summary] (e.g. to get the percentiles of the request durations)
+
+package hit
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// the durations are counted in log-linear buckets (like an HDR histogram):
+// each power of two is split into 2^subBits buckets of the same width
+// so that a bucket is within about 3% (1/2^subBits) of the durations it counts
+// whatever their magnitude (from nanoseconds to hours)
+const (
+	subBits    = 5
+	subBuckets = 1 << subBits
+	numBuckets = (63 - subBits + 1) * subBuckets // up to the maximum duration
+)
+
+// histogram counts the request durations (with atomic operations).
+type histogram struct {
+	counts [numBuckets]atomic.Int64
+}
+
+func (h *histogram) add(d time.Duration) {
+	h.counts[bucketOf(max(d, 0))].Add(1)
+}
+
+// snapshot returns the non-empty buckets of the histogram.
+func (h *histogram) snapshot() Histogram {
+	var hist Histogram
+	for i := range h.counts {
+		if n := h.counts[i].Load(); n > 0 {
+			lo, hi := bucketBounds(i)
+			hist = append(hist, Bucket{Min: lo, Max: hi, Count: int(n)})
+		}
+	}
+	return hist
+}
+
+// bucketOf returns the index of the bucket of d.
+func bucketOf(d time.Duration) int {
+	v := uint64(d)
+	if v < subBuckets {
+		return int(v) // the small durations have a bucket each
+	}
+	exp := bits.Len64(v) - 1                        // power of two of v (at least subBits)
+	sub := int(v>>(exp-subBits)) & (subBuckets - 1) // linear bucket within the power of two
+	return (exp-subBits+1)*subBuckets + sub
+}
+
+// bucketBounds returns the range [min, max) of the durations of a bucket.
+func bucketBounds(i int) (lo, hi time.Duration) {
+	if i < subBuckets {
+		return time.Duration(i), time.Duration(i + 1)
+	}
+	exp := i/subBuckets + subBits - 1
+	sub := uint64(i % subBuckets)
+	width := uint64(1) << (exp - subBits)
+	lower := (subBuckets + sub) * width
+	upper := lower + width
+	if upper > math.MaxInt64 {
+		upper = math.MaxInt64
+	}
+	return time.Duration(lower), time.Duration(upper)
+}
+
+// Histogram is the distribution of the request durations of a [Summary]:
+// its non-empty buckets in increasing order of durations.
+type Histogram []Bucket
+
+// Bucket counts the request durations in the range [Min, Max).
+type Bucket struct {
+	Min, Max time.Duration
+	Count    int
+}
+
+// Count returns the number of durations of the histogram.
+func (h Histogram) Count() int {
+	n := 0
+	for _, b := range h {
+		n += b.Count
+	}
+	return n
+}
+
+// Percentile returns the p-th percentile (0 to 100) of the durations (0 if the histogram is empty),
+// i.e. the highest duration of the bucket of the p-th percentile (within about 3% of the exact value).
+func (h Histogram) Percentile(p float64) time.Duration {
+	total := h.Count()
+	if total == 0 {
+		return 0
+	}
+
+	// the rank of the percentile (at least the first duration)
+	// (less a rounding error, e.g. 99.9% of 10000 is 9990.000000000002)
+	rank := max(int(math.Ceil(p/100*float64(total)-1e-9)), 1)
+
+	seen := 0
+	for _, b := range h {
+		seen += b.Count
+		if seen >= rank {
+			return b.Max - 1
+		}
+	}
+	return h[len(h)-1].Max - 1
+}
@@ -0,0 +1,65 @@
+package hit
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// test that the buckets cover all the durations without gaps
+func TestHistogramBuckets(t *testing.T) {
+
+	prev := time.Duration(0)
+	for i := range numBuckets {
+		lo, hi := bucketBounds(i)
+		if lo != prev || hi <= lo {
+			t.Fatalf("bucket %d: got = [%d, %d), want it to start at %d\n", i, lo, hi, prev)
+		}
+		if got := bucketOf(lo); got != i {
+			t.Fatalf("bucketOf(%d): got = %d, want = %d\n", lo, got, i)
+		}
+		if got := bucketOf(hi - 1); got != i {
+			t.Fatalf("bucketOf(%d): got = %d, want = %d\n", hi-1, got, i)
+		}
+		prev = hi
+	}
+}
+
+// test that the percentiles are within a bucket width (about 3%) of the exact values
+func TestSummaryPercentile(t *testing.T) {
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+
+	var results []Result
+	for range 10000 {
+		d := time.Duration(rnd.ExpFloat64() * float64(50*time.Millisecond)) // a long tail
+		results = append(results, Result{Duration: d})
+	}
+
+	s := Summarize(Results(slices.Values(results)))
+
+	durations := make([]time.Duration, len(results))
+	for i, r := range results {
+		durations[i] = r.Duration
+	}
+	slices.Sort(durations)
+
+	for _, p := range []float64{50, 90, 95, 99, 99.9} {
+		exact := durations[int(p/100*float64(len(durations)))-1]
+		got := s.Percentile(p)
+		if diff := float64(got-exact) / float64(exact); diff < 0 || diff > 1.0/subBuckets {
+			t.Errorf("Percentile(%v): got = %v, want = %v (up to a bucket width more)\n", p, got, exact)
+		}
+	}
+
+	if got := s.Percentile(100); got != s.Slowest {
+		t.Errorf("Percentile(100): got = %v, want = %v (the slowest)\n", got, s.Slowest)
+	}
+	if got := s.Percentile(0); got != s.Fastest {
+		t.Errorf("Percentile(0): got = %v, want = %v (the fastest)\n", got, s.Fastest)
+	}
+	if got := s.Histogram.Count(); got != len(results) {
+		t.Errorf("Histogram.Count(): got = %d, want = %d\n", got, len(results))
+	}
+}
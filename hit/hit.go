@@ -4,12 +4,21 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"time"
+
+	"github.com/faizan2786/gobyexample/pipeline"
 )
 
 // Send sends an HTTP request and returns its performance metric as [Result].
 func Send(client *http.Client, req *http.Request) Result {
+	return SendWithHook(client, req, nil)
+}
+
+// SendWithHook is like [Send] but calls afterResponse (if not nil)
+// when the response headers are received, before the response body is drained (see [Hooks]).
+func SendWithHook(client *http.Client, req *http.Request, afterResponse func(*http.Response)) Result {
 	var (
 		bytes  int64
 		status int
@@ -23,8 +32,13 @@ func Send(client *http.Client, req *http.Request) Result {
 	if err == nil {
 		defer res.Body.Close()
 		status = res.StatusCode
+		captureResponse(req, res) // (only when dumped, see Dump)
+		if afterResponse != nil {
+			afterResponse(res)
+		}
 		// we just need to know number of bytes in the response
 		// so stream the response efficiently (vi io.copy) and discard its content
+		// (unless it's captured to be dumped)
 		bytes, err = io.Copy(io.Discard, res.Body)
 	}
 
@@ -40,6 +54,14 @@ func Send(client *http.Client, req *http.Request) Result {
 // It returns a [Results] iterator that
 // pushes a [Result] for each [http.Request] sent.
 func SendN(ctx context.Context, N int, opts Options, req *http.Request) (Results, error) {
+	return SendTargets(ctx, N, opts, Target{Request: req})
+}
+
+// SendTargets sends N requests using [Send], picking a [Target] for each request
+// according to the target weights. All targets share the same rate limit and concurrency.
+// It returns a [Results] iterator that
+// pushes a [Result] (tagged with its target's name) for each [http.Request] sent.
+func SendTargets(ctx context.Context, N int, opts Options, targets ...Target) (Results, error) {
 
 	// fills opts with default values for unset/invalid options
 	opts = withDefaults(opts)
@@ -48,22 +70,74 @@ func SendN(ctx context.Context, N int, opts Options, req *http.Request) (Results
 		return nil, fmt.Errorf("n must be greater than 0: got %d", N)
 	}
 
-	// create a new child context from the received context
-	// this new context will enable us trigger the cancellation in the pipeline even when the parent context is alive
+	m, err := newMix(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	// create new child contexts from the received context
+	// these new contexts will enable us trigger the cancellation in the pipeline even when the parent context is alive
 	// e.g. when the iterator stops early (when the consumer wants to consume only part of the results)
-	ctx, cancel := context.WithCancel(ctx)
+	rn := newRun(ctx, opts)
+
+	requests := produce(rn, N, m, opts)
+
+	results := runPipeline(rn, opts, requests)
+
+	return iterate(rn, pipeline.Values(results), N), nil
+}
 
-	results := runPipeline(ctx, N, opts, req)
+// iterate returns a [Results] iterator over the results of a pipeline run.
+// If the run is cancelled (and ReportSkipped is set), the iterator ends with a Skipped result
+// for each of the planned requests that were never sent
+// (planned is negative if unknown, then a single Skipped result stands for the rest of them).
+func iterate(rn *run, results iter.Seq[Result], planned int) Results {
 
 	// define an iterator with a yield function that
 	// reads a result from results channel and produces (i.e. yields) to the consumer
 	iter := func(yield func(Result) bool) {
-		defer cancel() // cancel the derived context right before returning - in turn, cause the pipeline to stop
+		defer rn.stop() // stop the run right before returning - in turn, cause the pipeline to stop
+
+		sent := 0 // number of measured requests sent
 		for result := range results {
+			if !result.Warmup {
+				sent++
+			}
+			if f := rn.hooks.OnResult; f != nil {
+				f(result)
+			}
 			if !yield(result) {
 				return
 			}
 		}
+
+		// the run was cancelled before all the planned requests were sent
+		if !rn.reportSkipped || rn.ctx.Err() == nil {
+			return
+		}
+
+		// the requests produced but never sent (left in the pipeline)
+		// and the ones never produced: the rest of the planned requests
+		// unless the producer ran out of them first (e.g. a feeder out of records)
+		// or a single one for the rest of the requests if their number is unknown (e.g. a streamed log)
+		skipped := int(rn.produced.Load()) - sent
+		switch {
+		case rn.exhausted.Load():
+		case planned < 0:
+			skipped++
+		default:
+			skipped = planned - sent
+		}
+
+		r := Result{Skipped: true, Error: context.Cause(rn.ctx)}
+		for range skipped {
+			if f := rn.hooks.OnResult; f != nil {
+				f(r)
+			}
+			if !yield(r) {
+				return
+			}
+		}
 	}
 
 	// Note: we use iterator instead of a slice as
@@ -72,5 +146,5 @@ func SendN(ctx context.Context, N int, opts Options, req *http.Request) (Results
 	// (i.e. if something goes wrong or consumer wants to stop receiving further values)
 	// hence, saving further compute and memory allocations
 
-	return iter, nil
+	return iter
 }
@@ -0,0 +1,103 @@
+package hit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// a no-op Send function (i.e. the benchmarks measure the overhead of the pipeline)
+func noopSend(_ *http.Request) Result {
+	return Result{Status: http.StatusOK}
+}
+
+// benchmarking the ordered delivery of the results against the unordered one
+func BenchmarkSendNOrdered(b *testing.B) {
+	const N int = 1000
+
+	req := getTestHttpRequest()
+
+	for _, ordered := range []bool{false, true} {
+		name := "Unordered"
+		if ordered {
+			name = "Ordered"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			opts := Options{Concurrency: 8, Send: noopSend, Ordered: ordered}
+			for b.Loop() {
+				results, _ := SendN(context.Background(), N, opts, req)
+				for range results {
+				}
+			}
+		})
+	}
+}
+
+// benchmarking the overhead of the pipeline at different concurrency levels
+// (the allocations are reported per run of N requests)
+func BenchmarkSendNConcurrency(b *testing.B) {
+	const N int = 1000
+
+	req := getTestHttpRequest()
+
+	for _, c := range []int{1, 8} {
+		b.Run(fmt.Sprintf("C%d", c), func(b *testing.B) {
+			b.ReportAllocs()
+			opts := Options{Concurrency: c, Send: noopSend}
+			for b.Loop() {
+				results, _ := SendN(context.Background(), N, opts, req)
+				_ = Summarize(results)
+			}
+		})
+	}
+}
+
+// benchmarking the buffered handoffs between the stages (see Options.Buffer) against the unbuffered ones
+func BenchmarkSendNBuffer(b *testing.B) {
+	const N int = 1000
+
+	req := getTestHttpRequest()
+
+	for _, c := range []int{1, 8} {
+		for _, buffer := range []int{0, 64} {
+			b.Run(fmt.Sprintf("C%d/Buffer%d", c, buffer), func(b *testing.B) {
+				b.ReportAllocs()
+				opts := Options{Concurrency: c, Buffer: buffer, Send: noopSend}
+				for b.Loop() {
+					results, _ := SendN(context.Background(), N, opts, req)
+					_ = Summarize(results)
+				}
+			})
+		}
+	}
+}
+
+// benchmarking the (lock-free) aggregation of the results by concurrent goroutines
+func BenchmarkSummarizerAdd(b *testing.B) {
+	b.ReportAllocs()
+
+	sz := NewSummarizer()
+	r := Result{Status: http.StatusOK, Bytes: 100, Duration: time.Millisecond, Target: "items"}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sz.Add(r)
+		}
+	})
+}
+
+func BenchmarkMetricsAdd(b *testing.B) {
+	b.ReportAllocs()
+
+	m := NewMetrics()
+	r := Result{Status: http.StatusOK, Bytes: 100, Duration: time.Millisecond, Target: "items"}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.Add(r)
+		}
+	})
+}
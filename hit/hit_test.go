@@ -2,9 +2,11 @@ package hit
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -130,3 +132,276 @@ func TestSendN(t *testing.T) {
 		t.Errorf("SendN() returned %d results; want %d\n results", gotN, N)
 	}
 }
+
+// test that the warm-up requests are sent before (and in addition to) the N measured requests
+func TestSendNWithWarmup(t *testing.T) {
+	const (
+		N      int = 20
+		warmup int = 5
+	)
+
+	opts := Options{Warmup: warmup}
+	opts.Send = func(_ *http.Request) Result {
+		return Result{Status: http.StatusOK, Duration: time.Millisecond}
+	}
+
+	results, err := SendN(context.Background(), N, opts, getTestHttpRequest())
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+
+	s := Summarize(results)
+
+	if s.Requests != N {
+		t.Errorf("Requests: got = %d, want = %d\n", s.Requests, N)
+	}
+
+	if s.Warmup == nil {
+		t.Fatalf("Warmup = <nil>; want a summary of %d requests\n", warmup)
+	}
+	if s.Warmup.Requests != warmup {
+		t.Errorf("Warmup.Requests: got = %d, want = %d\n", s.Warmup.Requests, warmup)
+	}
+}
+
+// test that each result carries the metadata of its request
+func TestSendNMetadata(t *testing.T) {
+	const N int = 10
+
+	opts := Options{Concurrency: 2}
+	opts.Send = func(_ *http.Request) Result {
+		return Result{Status: http.StatusOK}
+	}
+
+	req := getTestHttpRequest()
+	results, err := SendN(context.Background(), N, opts, req)
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+
+	seen := make(map[int]bool)
+	for r := range results {
+		seen[r.Seq] = true
+
+		if r.Worker < 0 || r.Worker >= opts.Concurrency {
+			t.Errorf("Worker: got = %d, want in [0, %d)\n", r.Worker, opts.Concurrency)
+		}
+		if r.Method != req.Method || r.URL != req.URL.String() {
+			t.Errorf("request: got = %s %s, want = %s %s\n", r.Method, r.URL, req.Method, req.URL)
+		}
+		if r.Start.IsZero() || r.End.Before(r.Start) {
+			t.Errorf("timestamps: got = [%v, %v], want a valid interval\n", r.Start, r.End)
+		}
+	}
+
+	for seq := range N {
+		if !seen[seq] {
+			t.Errorf("no result for request %d\n", seq)
+		}
+	}
+}
+
+// test that the ordered mode delivers the results in the order of the requests
+// while keeping at most ReorderBuffer requests in flight
+func TestSendNOrdered(t *testing.T) {
+	const N int = 20
+
+	synctest.Test(t, func(t *testing.T) {
+		opts := Options{Concurrency: 4, Ordered: true, ReorderBuffer: 3}
+
+		var (
+			mu                 sync.Mutex
+			sent, inFlight, hi int
+		)
+
+		// every 5th request is slow (i.e. completes after the ones sent after it)
+		opts.Send = func(_ *http.Request) Result {
+			mu.Lock()
+			d := time.Millisecond
+			if sent%5 == 0 {
+				d = 10 * time.Millisecond
+			}
+			sent++
+			inFlight++
+			hi = max(hi, inFlight)
+			mu.Unlock()
+
+			time.Sleep(d)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return Result{Status: http.StatusOK, Duration: d}
+		}
+
+		results, err := SendN(context.Background(), N, opts, getTestHttpRequest())
+		if err != nil {
+			t.Fatalf("SendN() = %v; want no error\n", err)
+		}
+
+		var got []int
+		for r := range results {
+			got = append(got, r.Seq)
+		}
+
+		if len(got) != N {
+			t.Fatalf("SendN() returned %d results; want %d\n", len(got), N)
+		}
+		for i, seq := range got {
+			if seq != i {
+				t.Fatalf("sequence: got = %v, want = 0 to %d in order\n", got, N-1)
+			}
+		}
+
+		// the buffer (smaller than the concurrency) bounds the requests in flight
+		if hi > opts.ReorderBuffer {
+			t.Errorf("requests in flight: got = %d, want at most %d\n", hi, opts.ReorderBuffer)
+		}
+	})
+}
+
+// test that a cancelled run reports a partial summary
+// (the in-flight requests get a grace period to finish and the rest are skipped)
+func TestSendNCancelled(t *testing.T) {
+	const N int = 10
+
+	testCases := []struct {
+		name          string
+		grace         time.Duration
+		wantCompleted int
+		wantAbandoned int
+	}{
+		// the requests in flight at 15s finish at 20s
+		{name: "abandoned_in_flight", grace: 2 * time.Second, wantCompleted: 2, wantAbandoned: 2},
+		{name: "completed_in_grace", grace: 10 * time.Second, wantCompleted: 4, wantAbandoned: 0},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+
+				opts := Options{Concurrency: 2, Grace: tt.grace, ReportSkipped: true}
+
+				// a send function that takes 10s (unless its request is cancelled)
+				opts.Send = func(req *http.Request) Result {
+					select {
+					case <-time.After(10 * time.Second):
+						return Result{Status: http.StatusOK, Duration: 10 * time.Second}
+					case <-req.Context().Done():
+						return Result{Error: req.Context().Err()}
+					}
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+				defer cancel()
+
+				results, err := SendN(ctx, N, opts, getTestHttpRequest())
+				if err != nil {
+					t.Fatalf("SendN() = %v; want no error\n", err)
+				}
+
+				s := Summarize(results)
+
+				if !s.Partial {
+					t.Errorf("Partial = false; want true")
+				}
+				if s.Planned != N {
+					t.Errorf("Planned: got = %d, want = %d\n", s.Planned, N)
+				}
+				if s.Requests != tt.wantCompleted {
+					t.Errorf("Requests: got = %d, want = %d\n", s.Requests, tt.wantCompleted)
+				}
+				if s.Abandoned != tt.wantAbandoned {
+					t.Errorf("Abandoned: got = %d, want = %d\n", s.Abandoned, tt.wantAbandoned)
+				}
+				if s.Sent != 4 {
+					t.Errorf("Sent: got = %d, want = %d\n", s.Sent, 4)
+				}
+				if !errors.Is(s.Cause, context.DeadlineExceeded) {
+					t.Errorf("Cause: got = %v, want = %v\n", s.Cause, context.DeadlineExceeded)
+				}
+			})
+		})
+	}
+}
+
+// test that a buffered run (see Options.Buffer) delivers all the results
+// and that the requests buffered ahead of a cancelled run are reported as skipped (not as sent)
+func TestSendNBuffered(t *testing.T) {
+	const N int = 50
+
+	t.Run("completed", func(t *testing.T) {
+		opts := Options{Concurrency: 4, Buffer: 8, Send: noopSend}
+
+		results, err := SendN(context.Background(), N, opts, getTestHttpRequest())
+		if err != nil {
+			t.Fatalf("SendN() = %v; want no error\n", err)
+		}
+
+		s := Summarize(results)
+		if s.Requests != N || s.Partial {
+			t.Errorf("got = %d requests (partial=%t), want = %d requests\n", s.Requests, s.Partial, N)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			opts := Options{Concurrency: 2, Buffer: 8, ReportSkipped: true}
+			opts.Send = func(_ *http.Request) Result {
+				time.Sleep(time.Second)
+				return Result{Status: http.StatusOK, Duration: time.Second}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+			defer cancel()
+
+			results, err := SendN(ctx, N, opts, getTestHttpRequest())
+			if err != nil {
+				t.Fatalf("SendN() = %v; want no error\n", err)
+			}
+
+			sent, skipped := 0, 0
+			for r := range results {
+				if r.Skipped {
+					skipped++
+				} else {
+					sent++
+				}
+			}
+
+			if sent != 6 {
+				t.Errorf("sent: got = %d, want = %d\n", sent, 6)
+			}
+			if skipped != N-sent {
+				t.Errorf("skipped: got = %d, want = %d\n", skipped, N-sent)
+			}
+		})
+	})
+}
+
+// test that the requests of a cancelled run that were never sent are only reported if asked
+// (by default, the results only cover the requests that were sent)
+func TestSendNCancelledNoSkipped(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		opts := Options{}
+		opts.Send = func(_ *http.Request) Result {
+			time.Sleep(time.Second)
+			return Result{Status: http.StatusOK}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		defer cancel()
+
+		results, err := SendN(ctx, 10, opts, getTestHttpRequest())
+		if err != nil {
+			t.Fatalf("SendN() = %v; want no error\n", err)
+		}
+
+		for r := range results {
+			if r.Skipped || r.Error != nil {
+				t.Errorf("got = %+v, want only the results of the requests sent\n", r)
+			}
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package hit
+
+import "net/http"
+
+// Hooks are callbacks invoked at the events of a run (see [Options]).
+// A nil hook is skipped.
+//
+// BeforeRequest and AfterResponse are called concurrently by the dispatch workers
+// (hence, they must be safe for concurrent use), but each call gets its own request or response.
+// OnResult is called by the goroutine that iterates the results (i.e. one result at a time).
+type Hooks struct {
+	// OnStart is called once when the run starts (before the first request is sent).
+	OnStart func()
+
+	// BeforeRequest is called right before each request is sent
+	// (e.g. to add headers). The request can be modified but not replaced.
+	BeforeRequest func(req *http.Request)
+
+	// AfterResponse is called when the response headers are received, before its body is drained
+	// (e.g. to inspect a header or read part of the body).
+	// It's only called by the default Send function: it's NOT called with a custom Send function
+	// unless the function calls it itself (see [SendWithHook]).
+	AfterResponse func(res *http.Response)
+
+	// OnResult is called with each result right before it's delivered to the consumer
+	// (including the skipped results of a cancelled run, see [Options]).
+	// As it's called by the consumer's goroutine, it only sees the results the consumer reads:
+	// if the consumer stops early (e.g. breaks out of the loop), the rest of the results
+	// (e.g. of the requests in flight) are dropped without being passed to it.
+	OnResult func(r Result)
+
+	// OnEnd is called once when the run ends
+	// (all results are delivered or the consumer stopped reading them)
+	// with the cancellation cause of the run (nil if it wasn't cancelled).
+	OnEnd func(err error)
+}
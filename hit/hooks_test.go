@@ -0,0 +1,124 @@
+package hit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// test that the hooks are called at the events of a run
+func TestHooks(t *testing.T) {
+	const N int = 20
+
+	// a server that echoes the X-Test header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Echo", req.Header.Get("X-Test"))
+	}))
+	defer server.Close()
+
+	var (
+		starts, ends, results, echoes atomic.Int64
+		endErr                        error
+	)
+
+	opts := Options{Concurrency: 4}
+	opts.Hooks = Hooks{
+		OnStart: func() { starts.Add(1) },
+		BeforeRequest: func(req *http.Request) {
+			req.Header.Set("X-Test", "hooked")
+		},
+		AfterResponse: func(res *http.Response) {
+			if res.Header.Get("X-Echo") == "hooked" {
+				echoes.Add(1)
+			}
+		},
+		OnResult: func(Result) { results.Add(1) },
+		OnEnd: func(err error) {
+			ends.Add(1)
+			endErr = err
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+	res, err := SendN(context.Background(), N, opts, req)
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+	s := Summarize(res)
+
+	if s.Errors != 0 {
+		t.Fatalf("Errors: got = %d, want = %d\n", s.Errors, 0)
+	}
+	if starts.Load() != 1 || ends.Load() != 1 {
+		t.Errorf("OnStart, OnEnd calls: got = %d, %d, want = 1, 1\n", starts.Load(), ends.Load())
+	}
+	if endErr != nil {
+		t.Errorf("OnEnd error: got = %v, want = <nil>\n", endErr)
+	}
+	if echoes.Load() != int64(N) {
+		t.Errorf("responses with the header set by BeforeRequest: got = %d, want = %d\n", echoes.Load(), N)
+	}
+	if results.Load() != int64(N) {
+		t.Errorf("OnResult calls: got = %d, want = %d\n", results.Load(), N)
+	}
+}
+
+// test that OnEnd gets the cancellation cause of a cancelled run
+func TestHooksCancelled(t *testing.T) {
+
+	cause := errors.New("stopped by the test")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	var endErr error
+	opts := Options{Send: noopSend}
+	opts.Hooks.OnEnd = func(err error) { endErr = err }
+
+	res, err := SendN(ctx, 1000, opts, getTestHttpRequest())
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+
+	for range res {
+		cancel(cause) // cancel after the first result
+	}
+
+	if !errors.Is(endErr, cause) {
+		t.Errorf("OnEnd error: got = %v, want = %v\n", endErr, cause)
+	}
+}
+
+// test that OnResult only sees the results read by the consumer
+// and that AfterResponse isn't called with a custom Send function
+func TestHooksConsumerStops(t *testing.T) {
+	var results, responses atomic.Int64
+
+	opts := Options{Concurrency: 4}
+	opts.Send = func(*http.Request) Result { return Result{Status: http.StatusOK} }
+	opts.Hooks = Hooks{
+		AfterResponse: func(*http.Response) { responses.Add(1) },
+		OnResult:      func(Result) { results.Add(1) },
+	}
+
+	res, err := SendN(context.Background(), 100, opts, getTestHttpRequest())
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+
+	read := 0
+	for range res {
+		if read++; read == 10 {
+			break
+		}
+	}
+
+	if results.Load() != int64(read) {
+		t.Errorf("OnResult calls: got = %d, want = %d\n", results.Load(), read)
+	}
+	if responses.Load() != 0 {
+		t.Errorf("AfterResponse calls: got = %d, want = %d\n", responses.Load(), 0)
+	}
+}
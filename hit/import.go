@@ -0,0 +1,430 @@
+// This file defines importers that build a [Scenario] from requests captured elsewhere:
+// a HAR file exported from the browser's devtools or a curl command line (e.g. devtools' "Copy as cURL")
+
+package hit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// har is the part of the HAR (HTTP Archive) format needed to rebuild the requests.
+type har struct {
+	Log struct {
+		Entries []struct {
+			Request harRequest `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harRequest struct {
+	Method  string    `json:"method"`
+	URL     string    `json:"url"`
+	Headers []harPair `json:"headers"`
+	Cookies []harPair `json:"cookies"`
+	Body    *struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	} `json:"postData"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// skipHeaders are the headers set by the http client itself (or by the browser for its own connection).
+var skipHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// ImportHAR builds a [Scenario] with a request for each entry of a HAR file read from r.
+// If filter is not nil, only the entries whose "METHOD url" matches filter are imported
+// (e.g. `^POST ` or `/api/`).
+func ImportHAR(r io.Reader, filter *regexp.Regexp) (*Scenario, error) {
+	var h har
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, fmt.Errorf("decoding har: %w", err)
+	}
+
+	s := &Scenario{}
+	for _, e := range h.Log.Entries {
+		req := e.Request
+		if filter != nil && !filter.MatchString(req.Method+" "+req.URL) {
+			continue
+		}
+
+		sr := ScenarioRequest{
+			Method: req.Method,
+			URL:    EscapeTemplate(req.URL),
+			Header: http.Header{},
+		}
+
+		for _, hdr := range req.Headers {
+			// skip http/2 pseudo headers (e.g. ":authority")
+			key := http.CanonicalHeaderKey(hdr.Name)
+			if strings.HasPrefix(hdr.Name, ":") || skipHeaders[key] {
+				continue
+			}
+			sr.Header.Add(key, EscapeTemplate(hdr.Value))
+		}
+
+		// some tools only list the cookies separately (instead of in a Cookie header)
+		if sr.Header.Get("Cookie") == "" && len(req.Cookies) > 0 {
+			cookies := make([]string, len(req.Cookies))
+			for i, c := range req.Cookies {
+				cookies[i] = c.Name + "=" + c.Value
+			}
+			sr.Header.Set("Cookie", EscapeTemplate(strings.Join(cookies, "; ")))
+		}
+
+		if req.Body != nil && req.Body.Text != "" {
+			sr.Body = EscapeTemplate(req.Body.Text)
+			if sr.Header.Get("Content-Type") == "" && req.Body.MimeType != "" {
+				sr.Header.Set("Content-Type", req.Body.MimeType)
+			}
+		}
+
+		sr.Name = requestName(req.Method, req.URL)
+		s.add(sr)
+	}
+
+	if len(s.Requests) == 0 {
+		return nil, fmt.Errorf("no har entries to import")
+	}
+	return s, nil
+}
+
+// ImportCurl builds a [Scenario] with the request of a curl command line
+// (e.g. `curl -X POST -H 'Content-Type: application/json' -d '{"id": 1}' https://example.com/items`).
+// The data of a "@file" value is read from the file. It returns an error for an unknown option
+// (the options that don't change the request, e.g. --compressed, are ignored).
+func ImportCurl(cmdline string) (*Scenario, error) {
+	args, err := splitArgs(cmdline)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && (args[0] == "curl" || strings.HasSuffix(args[0], "/curl")) {
+		args = args[1:]
+	}
+
+	var (
+		method  string
+		rawURL  string
+		data    []string
+		getData bool // -G sends the data in the query string
+		header  = http.Header{}
+	)
+
+	// apply applies a (long) curl option with its value to the request
+	apply := func(name, v string) error {
+		switch name {
+		case "--request":
+			method = strings.ToUpper(v)
+		case "--header":
+			key, val, ok := strings.Cut(v, ":")
+			switch {
+			case ok && strings.TrimSpace(val) == "":
+				header.Del(strings.TrimSpace(key)) // "Key:" removes the header
+			case ok:
+				header.Add(strings.TrimSpace(key), strings.TrimSpace(val))
+			case strings.HasSuffix(v, ";"):
+				header.Add(strings.TrimSpace(strings.TrimSuffix(v, ";")), "") // "Key;" sends an empty header
+			default:
+				return fmt.Errorf("invalid curl header %q", v)
+			}
+		case "--data", "--data-ascii", "--data-binary", "--data-raw", "--json":
+			// "@file" reads the data from a file (except for --data-raw)
+			if name != "--data-raw" && strings.HasPrefix(v, "@") {
+				b, err := readCurlFile(v[1:])
+				if err != nil {
+					return err
+				}
+				v = string(b)
+				if name != "--data-binary" {
+					v = strings.NewReplacer("\r", "", "\n", "").Replace(v) // (as curl does)
+				}
+			}
+			data = append(data, v)
+			if name == "--json" {
+				header.Set("Content-Type", "application/json")
+				header.Set("Accept", "application/json")
+			}
+		case "--data-urlencode":
+			// "name=content" encodes only the content and "name@file" the content of the file
+			if i := strings.IndexAny(v, "=@"); i >= 0 && v[i] == '@' {
+				b, err := readCurlFile(v[i+1:])
+				if err != nil {
+					return err
+				}
+				v = v[:i] + "=" + string(b)
+			}
+			if key, content, ok := strings.Cut(v, "="); ok && key != "" {
+				data = append(data, key+"="+url.QueryEscape(content))
+			} else {
+				data = append(data, url.QueryEscape(strings.TrimPrefix(v, "=")))
+			}
+		case "--cookie":
+			header.Add("Cookie", v)
+		case "--user":
+			header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+		case "--user-agent":
+			header.Set("User-Agent", v)
+		case "--referer":
+			header.Set("Referer", v)
+		case "--url":
+			rawURL = v
+		case "--head":
+			method = http.MethodHead
+		case "--get":
+			getData = true
+		}
+		return nil // (the other options don't change the request)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		// value returns the value of an option (the rest of the arg if attached, else the next arg)
+		value := func(name, attached string) (string, error) {
+			if attached != "" {
+				return attached, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("curl option %s requires a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			takesValue, known := curlOptions[arg]
+			if !known {
+				return nil, fmt.Errorf("unknown curl option %s", arg)
+			}
+			v := ""
+			if takesValue {
+				if v, err = value(arg, ""); err != nil {
+					return nil, err
+				}
+			}
+			if err := apply(arg, v); err != nil {
+				return nil, err
+			}
+
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// short options can be grouped (e.g. -sSL) and the last one can have its value attached (e.g. -XPOST)
+			for j := 1; j < len(arg); j++ {
+				name, known := curlShortOptions[arg[j]]
+				if !known {
+					return nil, fmt.Errorf("unknown curl option -%c", arg[j])
+				}
+				if !curlOptions[name] {
+					if err := apply(name, ""); err != nil {
+						return nil, err
+					}
+					continue
+				}
+
+				v, err := value("-"+arg[j:j+1], arg[j+1:])
+				if err != nil {
+					return nil, err
+				}
+				if err := apply(name, v); err != nil {
+					return nil, err
+				}
+				break
+			}
+
+		default:
+			rawURL = arg
+		}
+	}
+
+	if rawURL == "" {
+		return nil, fmt.Errorf("curl command has no url")
+	}
+
+	sr := ScenarioRequest{Method: method, Header: header}
+	body := strings.Join(data, "&") // curl joins multiple data options with &
+
+	switch {
+	case getData && body != "":
+		sep := "?"
+		if strings.Contains(rawURL, "?") {
+			sep = "&"
+		}
+		rawURL += sep + body
+	case body != "":
+		sr.Body = EscapeTemplate(body)
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/x-www-form-urlencoded") // same default as curl
+		}
+	}
+
+	// same default method as curl
+	if sr.Method == "" {
+		sr.Method = http.MethodGet
+		if sr.Body != "" {
+			sr.Method = http.MethodPost
+		}
+	}
+
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	sr.URL = EscapeTemplate(rawURL)
+
+	for _, values := range header {
+		for i, v := range values {
+			values[i] = EscapeTemplate(v)
+		}
+	}
+
+	sr.Name = requestName(sr.Method, rawURL)
+	return &Scenario{Requests: []ScenarioRequest{sr}}, nil
+}
+
+// curlOptions are the curl options imported (whether they take a value)
+// Those that don't change the request (e.g. --compressed or --output) are accepted and ignored.
+var curlOptions = map[string]bool{
+	"--request": true, "--header": true, "--data": true, "--data-ascii": true, "--data-binary": true,
+	"--data-raw": true, "--data-urlencode": true, "--json": true, "--cookie": true, "--user": true,
+	"--user-agent": true, "--referer": true, "--url": true, "--head": false, "--get": false,
+
+	// ignored
+	"--output": true, "--max-time": true, "--connect-timeout": true, "--write-out": true, "--proxy": true,
+	"--retry": true, "--cacert": true, "--cert": true, "--key": true, "--cookie-jar": true, "--resolve": true,
+	"--compressed": false, "--location": false, "--insecure": false, "--silent": false, "--show-error": false,
+	"--include": false, "--verbose": false, "--fail": false, "--fail-with-body": false, "--no-buffer": false,
+	"--globoff": false, "--http1.1": false, "--http2": false, "--location-trusted": false, "--no-progress-meter": false,
+}
+
+// curlShortOptions are the long names of the short curl options (see [curlOptions]).
+var curlShortOptions = map[byte]string{
+	'X': "--request", 'H': "--header", 'd': "--data", 'b': "--cookie", 'u': "--user", 'A': "--user-agent",
+	'e': "--referer", 'I': "--head", 'G': "--get", 'o': "--output", 'm': "--max-time", 'w': "--write-out",
+	'x': "--proxy", 'c': "--cookie-jar", 'L': "--location", 'k': "--insecure", 's': "--silent",
+	'S': "--show-error", 'i': "--include", 'v': "--verbose", 'f': "--fail", 'N': "--no-buffer", 'g': "--globoff",
+}
+
+// readCurlFile reads the file of a curl "@file" value (stdin, i.e. "@-", isn't supported).
+func readCurlFile(name string) ([]byte, error) {
+	if name == "-" {
+		return nil, fmt.Errorf("curl data from stdin (@-) isn't supported")
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the curl data file: %w", err)
+	}
+	return b, nil
+}
+
+// requestName returns a readable name for a request, e.g. "GET /items".
+func requestName(method, rawURL string) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+	}
+	return method + " " + path
+}
+
+// splitArgs splits a (POSIX shell like) command line into its args.
+// It handles single and double quotes, ANSI-C quotes ($'...' as used by the browsers' "Copy as cURL"),
+// backslash escapes and line continuations.
+func splitArgs(cmdline string) ([]string, error) {
+	var (
+		args  []string
+		arg   strings.Builder
+		inArg bool // an arg is being built (it may still be empty, e.g. '')
+		quote rune // the current quote: ', " or $ for $'...' (0 if not in quotes)
+	)
+
+	// escapes in ANSI-C quotes
+	ansiEscapes := map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"'}
+
+	runes := []rune(cmdline)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '\\' && quote != '\'':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash in command line")
+			}
+			i++
+			next := runes[i]
+			if next == '\n' && quote == 0 {
+				continue // line continuation
+			}
+			inArg = true
+
+			switch {
+			case quote == '$':
+				if e, ok := ansiEscapes[next]; ok {
+					arg.WriteRune(e)
+				} else {
+					arg.WriteRune('\\')
+					arg.WriteRune(next)
+				}
+			case quote == '"' && !strings.ContainsRune(`"\$`+"`", next):
+				// inside double quotes a backslash only escapes a few characters
+				arg.WriteRune('\\')
+				arg.WriteRune(next)
+			default:
+				arg.WriteRune(next)
+			}
+
+		case quote != 0:
+			if r == quote || (quote == '$' && r == '\'') {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			quote = '$'
+			inArg = true
+			i++
+
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command line")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
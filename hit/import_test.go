@@ -0,0 +1,250 @@
+package hit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testHAR = `{
+  "log": {
+    "entries": [
+      {
+        "request": {
+          "method": "GET",
+          "url": "https://example.com/items?page=1",
+          "headers": [
+            {"name": ":authority", "value": "example.com"},
+            {"name": "accept", "value": "application/json"},
+            {"name": "content-length", "value": "0"}
+          ],
+          "cookies": [{"name": "session", "value": "abc"}, {"name": "theme", "value": "dark"}]
+        }
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://example.com/items",
+          "headers": [{"name": "Cookie", "value": "session=abc"}],
+          "postData": {"mimeType": "application/json", "text": "{\"name\": \"{{not a template}}\"}"}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "https://example.com/items?page=2", "headers": []}
+      }
+    ]
+  }
+}`
+
+func TestImportHAR(t *testing.T) {
+
+	s, err := ImportHAR(strings.NewReader(testHAR), nil)
+	if err != nil {
+		t.Fatalf("ImportHAR() = %v; want no error\n", err)
+	}
+
+	var names []string
+	for _, r := range s.Requests {
+		names = append(names, r.Name)
+	}
+	want := []string{"GET /items", "POST /items", "GET /items #2"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("names: got = %q, want = %q\n", names, want)
+	}
+
+	get := s.Requests[0]
+	if got := get.Header.Get("Accept"); got != "application/json" {
+		t.Errorf("Accept header: got = %q, want = %q\n", got, "application/json")
+	}
+	if got := get.Header.Get("Cookie"); got != "session=abc; theme=dark" {
+		t.Errorf("Cookie header: got = %q, want = %q\n", got, "session=abc; theme=dark")
+	}
+	if len(get.Header) != 2 {
+		t.Errorf("headers: got = %v, want only Accept and Cookie\n", get.Header)
+	}
+
+	// the imported requests must be sent exactly as captured
+	targets, err := s.Targets()
+	if err != nil {
+		t.Fatalf("Targets() = %v; want no error\n", err)
+	}
+	req, err := targets[1].Template.Request(context.Background(), TemplateData{})
+	if err != nil {
+		t.Fatalf("Request() = %v; want no error\n", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if got, want := string(body), `{"name": "{{not a template}}"}`; got != want {
+		t.Errorf("Body: got = %s, want = %s\n", got, want)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type header: got = %q, want = %q\n", got, "application/json")
+	}
+}
+
+func TestImportHARFilter(t *testing.T) {
+
+	s, err := ImportHAR(strings.NewReader(testHAR), regexp.MustCompile(`^POST `))
+	if err != nil {
+		t.Fatalf("ImportHAR() = %v; want no error\n", err)
+	}
+	if len(s.Requests) != 1 || s.Requests[0].Method != http.MethodPost {
+		t.Errorf("requests: got = %+v, want the POST request only\n", s.Requests)
+	}
+
+	if _, err := ImportHAR(strings.NewReader(testHAR), regexp.MustCompile(`^DELETE `)); err == nil {
+		t.Errorf("ImportHAR() error = <nil>; want an error for no matching entries")
+	}
+}
+
+func TestImportCurl(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		cmdline string
+		want    ScenarioRequest
+	}{
+		{
+			name:    "get",
+			cmdline: `curl https://example.com/items`,
+			want:    ScenarioRequest{Name: "GET /items", Method: "GET", URL: "https://example.com/items", Header: http.Header{}},
+		},
+		{
+			name: "post_json_multiline",
+			cmdline: `curl 'https://example.com/items' \
+  -H 'content-type: application/json' \
+  -b 'session=abc' \
+  --data-raw $'{"name":"it\'s"}' \
+  --compressed`,
+			want: ScenarioRequest{
+				Name:   "POST /items",
+				Method: "POST",
+				URL:    "https://example.com/items",
+				Header: http.Header{"Content-Type": {"application/json"}, "Cookie": {"session=abc"}},
+				Body:   `{"name":"it's"}`,
+			},
+		},
+		{
+			name:    "form_data_with_method",
+			cmdline: `curl -X put -d a=1 -d "b=2" -u user:pass example.com/items/1`,
+			want: ScenarioRequest{
+				Name:   "PUT /items/1",
+				Method: "PUT",
+				URL:    "http://example.com/items/1",
+				Header: http.Header{
+					"Content-Type":  {"application/x-www-form-urlencoded"},
+					"Authorization": {"Basic dXNlcjpwYXNz"},
+				},
+				Body: "a=1&b=2",
+			},
+		},
+		{
+			name:    "get_with_data",
+			cmdline: `curl -G --data-urlencode "q=red shoes" https://example.com/search`,
+			want:    ScenarioRequest{Name: "GET /search", Method: "GET", URL: "https://example.com/search?q=red+shoes", Header: http.Header{}},
+		},
+		{
+			name:    "attached_and_grouped_options",
+			cmdline: `curl -sSL -XPOST -HX-Tenant:acme '-HX-Empty;' -H 'Accept:' -dname=x example.com/items`,
+			want: ScenarioRequest{
+				Name:   "POST /items",
+				Method: "POST",
+				URL:    "http://example.com/items",
+				Header: http.Header{
+					"Content-Type": {"application/x-www-form-urlencoded"},
+					"X-Tenant":     {"acme"},
+					"X-Empty":      {""},
+				},
+				Body: "name=x",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ImportCurl(tt.cmdline)
+			if err != nil {
+				t.Fatalf("ImportCurl() = %v; want no error\n", err)
+			}
+			if len(s.Requests) != 1 {
+				t.Fatalf("got %d requests; want 1\n", len(s.Requests))
+			}
+
+			// compare the requests via their JSON encoding
+			var got, want bytes.Buffer
+			(&Scenario{Requests: s.Requests}).Write(&got)
+			(&Scenario{Requests: []ScenarioRequest{tt.want}}).Write(&want)
+			if got.String() != want.String() {
+				t.Errorf("got:\n%s\nwant:\n%s", got.String(), want.String())
+			}
+		})
+	}
+}
+
+// test that the data of a "@file" value is read from the file (without its newlines, unless it's binary)
+func TestImportCurlDataFile(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(file, []byte("{\"id\": 1}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		option string
+		want   string
+	}{
+		{option: "-d", want: `{"id": 1}`},
+		{option: "--data-binary", want: "{\"id\": 1}\n"},
+		{option: "--data-raw", want: "@" + file}, // (no files for --data-raw)
+	}
+
+	for _, tt := range testCases {
+		s, err := ImportCurl("curl " + tt.option + " @" + file + " https://example.com/items")
+		if err != nil {
+			t.Fatalf("ImportCurl(%s) = %v; want no error\n", tt.option, err)
+		}
+		if got := s.Requests[0].Body; got != tt.want {
+			t.Errorf("%s: got = %q, want = %q\n", tt.option, got, tt.want)
+		}
+	}
+}
+
+func TestImportCurlInvalid(t *testing.T) {
+	for _, cmdline := range []string{
+		`curl`, `curl -H`, `curl 'https://example.com`,
+		`curl --unknown https://example.com`, `curl -sZ https://example.com`, // unknown options
+		`curl -d @- https://example.com`, `curl -d @missing.json https://example.com`,
+	} {
+		if _, err := ImportCurl(cmdline); err == nil {
+			t.Errorf("ImportCurl(%q) error = <nil>; want an error\n", cmdline)
+		}
+	}
+}
+
+// test that a written scenario can be read back
+func TestScenarioWriteRead(t *testing.T) {
+
+	s, err := ImportHAR(strings.NewReader(testHAR), nil)
+	if err != nil {
+		t.Fatalf("ImportHAR() = %v; want no error\n", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatalf("Write() = %v; want no error\n", err)
+	}
+
+	got, err := ReadScenario(&buf)
+	if err != nil {
+		t.Fatalf("ReadScenario() = %v; want no error\n", err)
+	}
+	if len(got.Requests) != len(s.Requests) {
+		t.Errorf("requests: got = %d, want = %d\n", len(got.Requests), len(s.Requests))
+	}
+}
@@ -0,0 +1,208 @@
+// This file defines live metrics of a run in the Prometheus text format
+// (e.g. to scrape them during a long run and show them next to the server's metrics)
+
+package hit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram buckets (in seconds)
+// (the default buckets of the Prometheus clients)
+var latencyBuckets = [...]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// errorClasses are the classes of the errors of the requests (see errorClass)
+var errorClasses = [...]string{"timeout", "canceled", "connection", "other"}
+
+// Metrics collects live metrics of the results labelled by target
+// and serves them in the Prometheus text format (it's an [http.Handler] for a /metrics endpoint).
+// The skipped results of a cancelled run aren't counted (the warm-up results are),
+// and the requests that couldn't be authenticated are only counted as auth errors (as in a [Summary]).
+// It is safe for concurrent use.
+//
+// Like a [Summarizer], it's lock-free: the counters are updated with atomic operations
+// so that concurrent calls to Add never wait for each other (or for a scrape).
+type Metrics struct {
+	// metrics of each target
+	// (the map is copied on write as the targets are added only once)
+	targets atomic.Pointer[map[string]*targetMetrics]
+}
+
+// targetMetrics are the metrics of a target.
+type targetMetrics struct {
+	requests   atomic.Int64
+	authErrors atomic.Int64                          // requests that couldn't be authenticated (never sent)
+	errors     [len(errorClasses)]atomic.Int64       // by error class (see errorClasses)
+	responses  counterMap[int]                       // by status code
+	bytes      atomic.Int64                          // response bytes
+	buckets    [len(latencyBuckets) + 1]atomic.Int64 // latency histogram (not cumulative, the last bucket is +Inf)
+	latencySum atomic.Int64                          // in nanoseconds
+}
+
+// NewMetrics returns a new [Metrics].
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// Add adds a result to the metrics.
+// It only updates a few counters (the metrics are formatted when they're scraped).
+func (m *Metrics) Add(r Result) {
+	if r.Skipped {
+		return
+	}
+
+	t := m.target(r.Target)
+
+	if isAuthError(r.Error) {
+		t.authErrors.Add(1)
+		return
+	}
+
+	t.requests.Add(1)
+	t.bytes.Add(r.Bytes)
+	if r.Error != nil {
+		t.errors[slices.Index(errorClasses[:], errorClass(r.Error))].Add(1)
+	} else {
+		t.responses.add(r.Status)
+	}
+
+	i, _ := slices.BinarySearch(latencyBuckets[:], r.Duration.Seconds()) // first bucket with le >= seconds
+	t.buckets[i].Add(1)
+	t.latencySum.Add(int64(r.Duration))
+}
+
+// target returns the metrics of a target (adding them if it's a new target).
+func (m *Metrics) target(name string) *targetMetrics {
+	for {
+		old := m.targets.Load()
+		if old != nil {
+			if t, ok := (*old)[name]; ok {
+				return t
+			}
+		}
+
+		// copy the map with the new target
+		// (and try again if another goroutine has changed it in between)
+		targets := make(map[string]*targetMetrics, 1)
+		if old != nil {
+			targets = maps.Clone(*old)
+		}
+		t := &targetMetrics{}
+		targets[name] = t
+		if m.targets.CompareAndSwap(old, &targets) {
+			return t
+		}
+	}
+}
+
+// errorClass returns the class of the error of a request
+// (timeout, canceled, connection or other).
+func errorClass(err error) string {
+	var (
+		netErr net.Error
+		opErr  *net.OpError
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &opErr):
+		return "connection" // e.g. connection refused or reset
+	default:
+		return "other"
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format to w.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+
+	// (the counters are read as they're written, a scrape may be off by the results in the middle of being added)
+	var targets map[string]*targetMetrics
+	if p := m.targets.Load(); p != nil {
+		targets = *p
+	}
+	names := slices.Sorted(maps.Keys(targets))
+
+	var b strings.Builder
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	header("hit_requests_total", "counter", "Number of completed requests.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "hit_requests_total{target=%s} %d\n", quote(name), targets[name].requests.Load())
+	}
+
+	header("hit_errors_total", "counter", "Number of failed requests by error class (timeout, canceled, connection or other).")
+	for _, name := range names {
+		t := targets[name]
+		for i, class := range errorClasses {
+			if n := t.errors[i].Load(); n > 0 {
+				fmt.Fprintf(&b, "hit_errors_total{target=%s,class=%s} %d\n", quote(name), quote(class), n)
+			}
+		}
+	}
+
+	header("hit_auth_errors_total", "counter", "Number of requests that couldn't be authenticated (never sent, excluded from the other metrics).")
+	for _, name := range names {
+		if n := targets[name].authErrors.Load(); n > 0 {
+			fmt.Fprintf(&b, "hit_auth_errors_total{target=%s} %d\n", quote(name), n)
+		}
+	}
+
+	header("hit_responses_total", "counter", "Number of responses by status code.")
+	for _, name := range names {
+		responses := targets[name].responses.snapshot()
+		for _, code := range slices.Sorted(maps.Keys(responses)) {
+			fmt.Fprintf(&b, "hit_responses_total{target=%s,code=\"%d\"} %d\n", quote(name), code, responses[code])
+		}
+	}
+
+	header("hit_response_bytes_total", "counter", "Number of bytes received in the response bodies.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "hit_response_bytes_total{target=%s} %d\n", quote(name), targets[name].bytes.Load())
+	}
+
+	header("hit_request_duration_seconds", "histogram", "Duration of the requests.")
+	for _, name := range names {
+		t := targets[name]
+		var cumulative int64
+		for i := range t.buckets {
+			cumulative += t.buckets[i].Load()
+			le := "+Inf"
+			if i < len(latencyBuckets) {
+				le = strconv.FormatFloat(latencyBuckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(&b, "hit_request_duration_seconds_bucket{target=%s,le=%q} %d\n", quote(name), le, cumulative)
+		}
+		fmt.Fprintf(&b, "hit_request_duration_seconds_sum{target=%s} %g\n", quote(name), time.Duration(t.latencySum.Load()).Seconds())
+		fmt.Fprintf(&b, "hit_request_duration_seconds_count{target=%s} %d\n", quote(name), cumulative)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// quote quotes a label value (escaping backslashes, double quotes and newlines).
+func quote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
@@ -0,0 +1,99 @@
+package hit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+
+	m := NewMetrics()
+	m.Add(Result{Target: "reads", Status: http.StatusOK, Bytes: 100, Duration: 20 * time.Millisecond})
+	m.Add(Result{Target: "reads", Status: http.StatusNotFound, Bytes: 10, Duration: 200 * time.Millisecond})
+	m.Add(Result{Target: "reads", Error: context.DeadlineExceeded, Duration: 15 * time.Second})
+	m.Add(Result{Target: "writes", Status: http.StatusCreated, Duration: 5 * time.Millisecond})
+	m.Add(Result{Skipped: true, Error: context.Canceled})                                 // not counted
+	m.Add(Result{Target: "writes", Error: &AuthError{Err: errors.New("invalid_client")}}) // only counted as an auth error
+
+	// scrape the metrics endpoint
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics = %v; want no error\n", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("reading /metrics = %v; want no error\n", err)
+	}
+	got := string(body)
+
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type: got = %q, want the Prometheus text format\n", ct)
+	}
+
+	want := []string{
+		"# TYPE hit_requests_total counter",
+		`hit_requests_total{target="reads"} 3`,
+		`hit_requests_total{target="writes"} 1`,
+		`hit_errors_total{target="reads",class="timeout"} 1`,
+		`hit_auth_errors_total{target="writes"} 1`,
+		`hit_responses_total{target="reads",code="200"} 1`,
+		`hit_responses_total{target="reads",code="404"} 1`,
+		`hit_response_bytes_total{target="reads"} 110`,
+		"# TYPE hit_request_duration_seconds histogram",
+		`hit_request_duration_seconds_bucket{target="reads",le="0.025"} 1`,
+		`hit_request_duration_seconds_bucket{target="reads",le="0.25"} 2`,
+		`hit_request_duration_seconds_bucket{target="reads",le="10"} 2`,
+		`hit_request_duration_seconds_bucket{target="reads",le="+Inf"} 3`,
+		`hit_request_duration_seconds_sum{target="reads"} 15.22`,
+		`hit_request_duration_seconds_count{target="reads"} 3`,
+		`hit_request_duration_seconds_bucket{target="writes",le="0.005"} 1`,
+		`hit_request_duration_seconds_count{target="writes"} 1`,
+	}
+	for _, line := range want {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("metrics: missing line %q in:\n%s", line, got)
+		}
+	}
+	if strings.Contains(got, `target=""`) {
+		t.Errorf("metrics: got the skipped result, want it excluded:\n%s", got)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+
+	testCases := []struct {
+		err  error
+		want string
+	}{
+		{err: context.DeadlineExceeded, want: "timeout"},
+		{err: fmt.Errorf("get: %w", context.Canceled), want: "canceled"},
+		{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: "connection"},
+		{err: errors.New("unexpected EOF"), want: "other"},
+	}
+
+	for _, tt := range testCases {
+		if got := errorClass(tt.err); got != tt.want {
+			t.Errorf("errorClass(%v): got = %q, want = %q\n", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+
+	if got, want := quote("GET /items?q=\"a\\b\"\n"), `"GET /items?q=\"a\\b\"\n"`; got != want {
+		t.Errorf("quote(): got = %s, want = %s\n", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+// This file defines middleware for the send function of a run
+// (e.g. to add headers, authenticate, log or inject faults into each request)
+
+package hit
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Middleware wraps a [SendFunc] with extra behaviour (e.g. to modify the request or the result).
+type Middleware func(next SendFunc) SendFunc
+
+// Chain wraps send with the middleware.
+// The first middleware is the outermost one (i.e. it sees the request first and the result last).
+func Chain(send SendFunc, middleware ...Middleware) SendFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		send = middleware[i](send)
+	}
+	return send
+}
+
+// WithHeader sets the header of each request (replacing the values set by its target).
+// Each request gets its own copy of the values (i.e. a later middleware can change them).
+func WithHeader(header http.Header) Middleware {
+	return func(next SendFunc) SendFunc {
+		return func(req *http.Request) Result {
+			for key, values := range header {
+				req.Header[http.CanonicalHeaderKey(key)] = slices.Clone(values)
+			}
+			return next(req)
+		}
+	}
+}
+
+// BasicAuth sets the basic authentication credentials of each request.
+// (see [BasicCredentials] to set them as the authentication of the run instead)
+func BasicAuth(username, password string) Middleware {
+	// (encode the credentials once rather than per request)
+	return WithHeader(http.Header{"Authorization": {basicAuthorization(username, password)}})
+}
+
+// BearerAuth sets a (static) bearer token in the Authorization header of each request.
+// (see [BearerToken] to set it as the authentication of the run instead)
+func BearerAuth(token string) Middleware {
+	return WithHeader(http.Header{"Authorization": {bearerAuthorization(token)}})
+}
+
+// RequestID sets a unique ID (a random UUID) in the given header of each request
+// (e.g. "X-Request-ID") unless the request already has one.
+func RequestID(header string) Middleware {
+	return func(next SendFunc) SendFunc {
+		return func(req *http.Request) Result {
+			setRequestID(req, header)
+			return next(req)
+		}
+	}
+}
+
+// Logging writes a line for each request to w once its result is known, e.g.
+//
+//	GET http://localhost:8082/items 200 512B 12ms
+//
+// The lines of concurrent requests are not interleaved.
+func Logging(w io.Writer) Middleware {
+	var mu sync.Mutex // guards w
+
+	return func(next SendFunc) SendFunc {
+		return func(req *http.Request) Result {
+			r := next(req)
+
+			line := fmt.Sprintf("%s %s %d %dB %s", req.Method, req.URL, r.Status, r.Bytes, r.Duration.Round(time.Millisecond))
+			if r.Error != nil {
+				line += " error: " + r.Error.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintln(w, line)
+
+			return r
+		}
+	}
+}
+
+// InjectLatency delays a ratio (0 to 1) of the requests by d before sending them
+// (e.g. to test how a slow server shows up in the summary).
+// The delay is added to the Duration of their results.
+func InjectLatency(d time.Duration, ratio float64) Middleware {
+	return func(next SendFunc) SendFunc {
+		return func(req *http.Request) Result {
+			if rand.Float64() >= ratio {
+				return next(req)
+			}
+
+			// wait for the delay (unless the request is cancelled)
+			t := time.NewTimer(d)
+			defer t.Stop()
+
+			start := time.Now()
+			select {
+			case <-t.C:
+			case <-req.Context().Done():
+				return Result{Duration: time.Since(start), Error: req.Context().Err()}
+			}
+
+			r := next(req)
+			r.Duration += d
+			return r
+		}
+	}
+}
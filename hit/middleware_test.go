@@ -0,0 +1,161 @@
+package hit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// a send function that records the requests it gets
+func recordingSend(got *[]*http.Request) SendFunc {
+	return func(req *http.Request) Result {
+		*got = append(*got, req)
+		return Result{Status: http.StatusOK, Bytes: 5, Duration: time.Millisecond}
+	}
+}
+
+// test that the first middleware of a chain is the outermost one
+func TestChain(t *testing.T) {
+
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next SendFunc) SendFunc {
+			return func(req *http.Request) Result {
+				order = append(order, name+" before")
+				r := next(req)
+				order = append(order, name+" after")
+				return r
+			}
+		}
+	}
+
+	var got []*http.Request
+	send := Chain(recordingSend(&got), mw("a"), mw("b"))
+	send(getTestHttpRequest())
+
+	want := []string{"a before", "b before", "b after", "a after"}
+	if strings.Join(order, ", ") != strings.Join(want, ", ") {
+		t.Errorf("order: got = %v, want = %v\n", order, want)
+	}
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+
+	testCases := []struct {
+		name   string
+		mw     Middleware
+		header string
+		want   string
+	}{
+		{name: "header", mw: WithHeader(http.Header{"x-tenant": {"acme"}}), header: "X-Tenant", want: "acme"},
+		{name: "basic_auth", mw: BasicAuth("user", "pass"), header: "Authorization", want: "Basic dXNlcjpwYXNz"},
+		{name: "bearer_auth", mw: BearerAuth("token"), header: "Authorization", want: "Bearer token"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []*http.Request
+			Chain(recordingSend(&got), tt.mw)(getTestHttpRequest())
+
+			if v := got[0].Header.Get(tt.header); v != tt.want {
+				t.Errorf("%s: got = %q, want = %q\n", tt.header, v, tt.want)
+			}
+		})
+	}
+}
+
+// test that a request changing its header values doesn't change them for the next requests
+func TestHeaderMiddlewareCopy(t *testing.T) {
+
+	var got []*http.Request
+	send := Chain(recordingSend(&got), WithHeader(http.Header{"X-Tenant": {"acme"}}))
+
+	send(getTestHttpRequest())
+	got[0].Header["X-Tenant"][0] = "changed"
+	send(getTestHttpRequest())
+
+	if v := got[1].Header.Get("X-Tenant"); v != "acme" {
+		t.Errorf("X-Tenant: got = %q, want = %q\n", v, "acme")
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+
+	var got []*http.Request
+	send := Chain(recordingSend(&got), RequestID("X-Request-ID"))
+	send(getTestHttpRequest())
+	send(getTestHttpRequest())
+
+	// an existing ID is kept
+	req := getTestHttpRequest()
+	req.Header.Set("X-Request-ID", "given")
+	send(req)
+
+	id1, id2 := got[0].Header.Get("X-Request-ID"), got[1].Header.Get("X-Request-ID")
+	if len(id1) != 36 || id1 == id2 {
+		t.Errorf("X-Request-ID: got = %q and %q, want 2 distinct uuids\n", id1, id2)
+	}
+	if id := got[2].Header.Get("X-Request-ID"); id != "given" {
+		t.Errorf("X-Request-ID: got = %q, want = %q\n", id, "given")
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+
+	var log strings.Builder
+	var got []*http.Request
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8082/items", http.NoBody)
+	Chain(recordingSend(&got), Logging(&log))(req)
+
+	if want := "POST http://localhost:8082/items 200 5B 1ms\n"; log.String() != want {
+		t.Errorf("log: got = %q, want = %q\n", log.String(), want)
+	}
+}
+
+func TestInjectLatency(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		var got []*http.Request
+
+		start := time.Now()
+		r := Chain(recordingSend(&got), InjectLatency(time.Second, 1))(getTestHttpRequest())
+
+		if d := time.Since(start); d != time.Second {
+			t.Errorf("delay: got = %v, want = %v\n", d, time.Second)
+		}
+		if want := time.Second + time.Millisecond; r.Duration != want {
+			t.Errorf("Duration: got = %v, want = %v\n", r.Duration, want)
+		}
+
+		// a cancelled request isn't delayed
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r = Chain(recordingSend(&got), InjectLatency(time.Second, 1))(getTestHttpRequest().WithContext(ctx))
+		if r.Error == nil || len(got) != 1 {
+			t.Errorf("cancelled request: got = %+v (%d sent), want an error (1 sent)\n", r, len(got))
+		}
+	})
+}
+
+// test that the middleware of the options wraps the send function of a run
+func TestOptionsMiddleware(t *testing.T) {
+
+	var got []*http.Request
+	opts := Options{Send: recordingSend(&got), Middleware: []Middleware{BearerAuth("token")}}
+
+	results, err := SendN(context.Background(), 3, opts, getTestHttpRequest())
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+	_ = Summarize(results)
+
+	for _, req := range got {
+		if v := req.Header.Get("Authorization"); v != "Bearer token" {
+			t.Errorf("Authorization: got = %q, want = %q\n", v, "Bearer token")
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// This file defines a monitor of the client itself (i.e. the hit process)
+// it samples the runtime during a run to tell whether the client (rather than the server)
+// is the bottleneck, e.g. when the client can't keep up with the requested rate
+
+package hit
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientSample is a sample of the client's runtime taken during a run.
+// The rates and averages cover the interval since the previous sample.
+type ClientSample struct {
+	Time       time.Time     // Time the sample was taken
+	Goroutines int           // Goroutines is the number of goroutines
+	CPU        float64       // CPU is the CPU usage of the process (in cores, 0 if unknown)
+	GCPause    time.Duration // GCPause is the total stop-the-world time of the garbage collector
+	SchedLag   time.Duration // SchedLag is how late the sample was taken (i.e. how long a ready goroutine waits to run)
+	Requests   int           // Requests is the number of completed (measured) requests
+	Latency    time.Duration // Latency is the average duration of the completed requests
+}
+
+// ClientStats summarizes the samples of a [ClientMonitor].
+type ClientStats struct {
+	Samples []ClientSample
+
+	Cores         int           // Cores is the number of cores the client can use (i.e. GOMAXPROCS)
+	CPU           float64       // CPU is the average CPU usage (in cores, 0 if unknown)
+	MaxCPU        float64       // MaxCPU is the maximum CPU usage of a sample
+	MaxGoroutines int           // MaxGoroutines is the maximum number of goroutines
+	GCPause       time.Duration // GCPause is the total stop-the-world time of the garbage collector
+	MaxSchedLag   time.Duration // MaxSchedLag is the maximum scheduling lag of a sample
+
+	RPS       float64 // RPS is the achieved request rate (over the sampled period)
+	TargetRPS int     // TargetRPS is the requested rate (0 if not rate limited)
+
+	// Saturated is true if the client is likely the bottleneck of the run
+	// (Warning explains why and suggests a fix)
+	Saturated bool
+	Warning   string
+}
+
+// ClientMonitor samples the client's runtime (goroutines, CPU usage, GC pauses and scheduling lag)
+// and the throughput and latency of the requests during a run (see [Options]).
+// A monitor is meant for a single run. Its methods are safe for concurrent use.
+type ClientMonitor struct {
+	// Interval between the samples
+	// Default: 250ms
+	Interval time.Duration
+
+	// completed (measured) requests and the sum of their durations (updated by the dispatch workers)
+	requests, latencySum atomic.Int64
+
+	mu          sync.Mutex
+	samples     []ClientSample
+	started     time.Time // start of the run
+	rps         int       // requested rate of the run
+	concurrency int       // concurrency of the run
+}
+
+// the thresholds of the saturation warning
+const (
+	saturationRPS      = 0.8                   // an achieved rate below 80% of the requested rate
+	saturationLatency  = 1.5                   // a latency that grows by less than 50% (i.e. stays flat)
+	saturationCPU      = 0.9                   // a CPU usage above 90% of the cores
+	saturationSchedLag = 20 * time.Millisecond // goroutines that wait long to run
+	saturationSamples  = 8                     // the samples needed to tell whether the latency stays flat (2s by default)
+)
+
+// start samples the runtime until stop is called.
+// It is safe to call on a nil monitor (it does nothing).
+func (m *ClientMonitor) start(opts Options) (stop func()) {
+	if m == nil {
+		return func() {}
+	}
+
+	interval := m.Interval
+	if interval <= 0 {
+		interval = 250 * time.Millisecond
+	}
+
+	m.mu.Lock()
+	m.started, m.rps, m.concurrency = time.Now(), opts.RPS, opts.Concurrency
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		prev := m.read(m.started)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		due := time.Now().Add(interval)
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				m.sample(&prev, time.Now(), 0) // the (partial) last interval
+				return
+			}
+
+			now := time.Now()
+			m.sample(&prev, now, max(now.Sub(due), 0))
+			due = due.Add(interval)
+			if due.Before(now) {
+				due = now.Add(interval) // skipped ticks (e.g. the process was suspended)
+			}
+		}
+	})
+
+	return sync.OnceFunc(func() {
+		close(done)
+		wg.Wait()
+	})
+}
+
+// observe records a completed request.
+// It is safe to call on a nil monitor (it does nothing).
+func (m *ClientMonitor) observe(r Result) {
+	if m == nil || r.Warmup || r.Abandoned {
+		return
+	}
+	m.requests.Add(1)
+	m.latencySum.Add(int64(r.Duration))
+}
+
+// counters holds the cumulative counters that the samples are the differences of.
+type counters struct {
+	time       time.Time
+	cpu        time.Duration
+	cpuOK      bool
+	gcPause    time.Duration
+	requests   int64
+	latencySum int64
+}
+
+const gcPauseMetric = "/sched/pauses/total/gc:seconds"
+
+func (m *ClientMonitor) read(now time.Time) counters {
+	c := counters{time: now, requests: m.requests.Load(), latencySum: m.latencySum.Load()}
+	c.cpu, c.cpuOK = cpuTime()
+
+	s := []metrics.Sample{{Name: gcPauseMetric}}
+	metrics.Read(s)
+	if s[0].Value.Kind() == metrics.KindFloat64Histogram {
+		c.gcPause = histogramSum(s[0].Value.Float64Histogram())
+	}
+	return c
+}
+
+// histogramSum approximates the sum of the values of a histogram (in seconds)
+// with the lower bound of their buckets.
+func histogramSum(h *metrics.Float64Histogram) time.Duration {
+	var sum float64
+	for i, n := range h.Counts {
+		if n > 0 && h.Buckets[i] > 0 {
+			sum += float64(n) * h.Buckets[i]
+		}
+	}
+	return time.Duration(sum * float64(time.Second))
+}
+
+// sample takes a sample of the interval since prev.
+func (m *ClientMonitor) sample(prev *counters, now time.Time, lag time.Duration) {
+	cur := m.read(now)
+	elapsed := cur.time.Sub(prev.time)
+	if elapsed <= 0 {
+		return
+	}
+
+	s := ClientSample{
+		Time:       now,
+		Goroutines: runtime.NumGoroutine(),
+		GCPause:    cur.gcPause - prev.gcPause,
+		SchedLag:   lag,
+		Requests:   int(cur.requests - prev.requests),
+	}
+	if cur.cpuOK && prev.cpuOK {
+		s.CPU = float64(cur.cpu-prev.cpu) / float64(elapsed)
+	}
+	if s.Requests > 0 {
+		s.Latency = time.Duration((cur.latencySum - prev.latencySum) / int64(s.Requests))
+	}
+	*prev = cur
+
+	m.mu.Lock()
+	m.samples = append(m.samples, s)
+	m.mu.Unlock()
+}
+
+// Stats returns the statistics of the samples taken so far.
+func (m *ClientMonitor) Stats() ClientStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := ClientStats{
+		Samples:   append([]ClientSample(nil), m.samples...),
+		Cores:     runtime.GOMAXPROCS(0),
+		TargetRPS: m.rps,
+	}
+	if len(m.samples) == 0 {
+		return st
+	}
+
+	var (
+		requests int
+		cpuSum   float64
+	)
+	for _, s := range m.samples {
+		requests += s.Requests
+		cpuSum += s.CPU
+		st.MaxCPU = max(st.MaxCPU, s.CPU)
+		st.MaxGoroutines = max(st.MaxGoroutines, s.Goroutines)
+		st.GCPause += s.GCPause
+		st.MaxSchedLag = max(st.MaxSchedLag, s.SchedLag)
+	}
+	st.CPU = cpuSum / float64(len(m.samples))
+
+	// the samples cover the period from the start of the run to the last sample
+	if period := m.samples[len(m.samples)-1].Time.Sub(m.started); period > 0 {
+		st.RPS = float64(requests) / period.Seconds()
+	}
+
+	st.Saturated, st.Warning = m.saturation(st)
+	return st
+}
+
+// saturation tells whether the client is likely the bottleneck of the run (and why).
+// A server that can't keep up makes the latency grow (the requests queue up on the server),
+// whereas a client that can't keep up sends fewer requests than requested at a flat latency.
+func (m *ClientMonitor) saturation(st ClientStats) (bool, string) {
+	var reasons []string
+
+	if st.Cores > 0 && st.CPU >= saturationCPU*float64(st.Cores) {
+		reasons = append(reasons, fmt.Sprintf("CPU at %.1f of %d cores", st.CPU, st.Cores))
+	}
+	if st.MaxSchedLag >= saturationSchedLag {
+		reasons = append(reasons, fmt.Sprintf("goroutines waited up to %s to run", st.MaxSchedLag.Round(time.Millisecond)))
+	}
+
+	if m.rps > 0 && st.RPS < saturationRPS*float64(m.rps) && latencyFlat(st.Samples) {
+		msg := fmt.Sprintf("the client is likely the bottleneck: achieved %.0f RPS of the requested %d while the latency stayed flat", st.RPS, m.rps)
+
+		// not enough concurrency for the requested rate (each worker sends one request at a time)
+		if latency := averageLatency(st.Samples); latency > 0 {
+			if maxRPS := float64(m.concurrency) / latency.Seconds(); maxRPS < float64(m.rps) {
+				reasons = append(reasons, fmt.Sprintf("increase the concurrency: %d worker(s) can't send more than %.0f RPS at %s latency", m.concurrency, maxRPS, latency.Round(time.Microsecond)))
+			}
+		}
+		if len(reasons) > 0 {
+			msg += " (" + strings.Join(reasons, ", ") + ")"
+		}
+		return true, msg
+	}
+
+	// an unlimited run is as fast as the client can go, hence, only a busy client is a bottleneck
+	if len(reasons) > 0 {
+		return true, "the client may be the bottleneck: " + strings.Join(reasons, ", ")
+	}
+	return false, ""
+}
+
+// latencyFlat reports whether the latency of the last third of the samples
+// didn't grow much compared to the first third.
+// A run too short to tell (too few samples or thirds without requests) isn't flat.
+func latencyFlat(samples []ClientSample) bool {
+	if len(samples) < saturationSamples {
+		return false
+	}
+	third := len(samples) / 3
+	first, last := averageLatency(samples[:third]), averageLatency(samples[len(samples)-third:])
+	if first == 0 || last == 0 {
+		return false
+	}
+	return float64(last) <= saturationLatency*float64(first)
+}
+
+// averageLatency returns the average latency of the requests of the samples.
+func averageLatency(samples []ClientSample) time.Duration {
+	var (
+		n   int
+		sum time.Duration
+	)
+	for _, s := range samples {
+		n += s.Requests
+		sum += time.Duration(s.Requests) * s.Latency
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / time.Duration(n)
+}
@@ -0,0 +1,80 @@
+package hit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestClientMonitor(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		concurrency   int
+		n             int                         // number of requests (60 if 0)
+		latency       func(n int64) time.Duration // latency of the nth request
+		wantSaturated bool
+	}{
+		// 2 workers at 100ms can't send more than 20 RPS (the latency stays flat)
+		{name: "client_bottleneck", concurrency: 2, latency: func(int64) time.Duration { return 100 * time.Millisecond }, wantSaturated: true},
+		// the latency grows as the server can't keep up
+		{name: "server_bottleneck", concurrency: 2, latency: func(n int64) time.Duration { return time.Duration(n) * 20 * time.Millisecond }},
+		// the requested rate is achieved
+		{name: "no_bottleneck", concurrency: 10, latency: func(int64) time.Duration { return 100 * time.Millisecond }},
+		// too short to tell whether the latency stays flat (a few samples)
+		{name: "short_run", concurrency: 2, n: 6, latency: func(int64) time.Duration { return 100 * time.Millisecond }},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				var sent atomic.Int64
+
+				m := &ClientMonitor{}
+				opts := Options{Concurrency: tt.concurrency, RPS: 50, Monitor: m}
+				opts.Send = func(_ *http.Request) Result {
+					d := tt.latency(sent.Add(1))
+					time.Sleep(d)
+					return Result{Status: http.StatusOK, Duration: d}
+				}
+
+				n := tt.n
+				if n == 0 {
+					n = 60
+				}
+				results, err := SendN(context.Background(), n, opts, getTestHttpRequest())
+				if err != nil {
+					t.Fatalf("SendN() = %v; want no error\n", err)
+				}
+				_ = Summarize(results)
+
+				st := m.Stats()
+				if len(st.Samples) == 0 {
+					t.Fatalf("Samples: got none, want some\n")
+				}
+				if st.TargetRPS != 50 {
+					t.Errorf("TargetRPS: got = %d, want = %d\n", st.TargetRPS, 50)
+				}
+				if st.Saturated != tt.wantSaturated {
+					t.Errorf("Saturated: got = %v (%s, %.1f RPS), want = %v\n", st.Saturated, st.Warning, st.RPS, tt.wantSaturated)
+				}
+				if tt.wantSaturated && !strings.Contains(st.Warning, "increase the concurrency") {
+					t.Errorf("Warning: got = %q, want a hint to increase the concurrency\n", st.Warning)
+				}
+			})
+		})
+	}
+}
+
+// test that a nil monitor is ignored by the run
+func TestClientMonitorNil(t *testing.T) {
+
+	var m *ClientMonitor
+	stop := m.start(Options{})
+	m.observe(Result{Duration: time.Millisecond})
+	stop()
+}
@@ -20,8 +20,81 @@ type Options struct {
 	RPS int
 
 	// a request processing function
+	// (the AfterResponse hook is only called by the default one, see [Hooks])
 	// Default: uses [Send].
 	Send SendFunc
+
+	// middleware wrapping the Send function (see [Chain])
+	// Default: nil (no middleware)
+	Middleware []Middleware
+
+	// a data feeder that provides a record to the template of each request
+	// Default: nil (no data)
+	Feeder *Feeder
+
+	// number of warm-up requests sent before the measured requests
+	// (e.g. to set up the connections and warm up the server's caches)
+	// Their results are flagged as Warmup and summarized separately.
+	// Default: 0 (no warm-up)
+	Warmup int
+
+	// duration of the warm-up phase (measured from the first request sent, excluding the pauses)
+	// If both Warmup and WarmupDuration are set, the warm-up lasts until both are reached.
+	// Default: 0 (no warm-up)
+	WarmupDuration time.Duration
+
+	// pauses and resumes the requests while the run is in progress
+	// Default: nil (never paused)
+	Pauser *Pauser
+
+	// time given to the in-flight requests to finish when the run is cancelled
+	// (the requests still in flight at the end of it are abandoned)
+	// Default: 0 (the in-flight requests are abandoned right away)
+	Grace time.Duration
+
+	// delivers a Skipped result (with the cancellation cause as its Error) for each planned request
+	// that was never sent because the run was cancelled (a single one for the rest of the log of a [Replay])
+	// so that a [Summary] of the results is flagged as partial with its cause (see [Summary.Partial])
+	// Default: false (the results only cover the requests that were sent)
+	ReportSkipped bool
+
+	// delivers the results in the order of the requests (i.e. by Seq) instead of as they complete
+	// Default: false
+	Ordered bool
+
+	// maximum number of requests in flight (or waiting to be delivered) in the ordered mode
+	// A slow request holds back the results after it (up to this many of them are buffered)
+	// and the new requests wait when the buffer is full, which caps the memory
+	// but lowers the throughput (and delays the results) behind a slow request.
+	// It shouldn't be lower than Concurrency (it bounds the requests in flight as well).
+	// Default: 4 × Concurrency
+	ReorderBuffer int
+
+	// number of requests (and results) buffered between the pipeline stages
+	// i.e. the high-throughput mode: the stages hand off the requests and results through buffers
+	// instead of one at a time, which saves a goroutine handoff per request while the buffers aren't empty.
+	// The requests are produced ahead of the workers (hence, a WarmupDuration ends up to Buffer requests late).
+	// With RPS, only the results are buffered (the requests are handed off at their own time).
+	// Default: 0 (unbuffered)
+	Buffer int
+
+	// monitors the client during the run to tell whether it's the bottleneck (see [ClientMonitor.Stats])
+	// Default: nil (not monitored)
+	Monitor *ClientMonitor
+
+	// starts a new W3C trace for each request (a sampled traceparent header) and sets its X-Request-ID
+	// (unless its target sets one). Both IDs are recorded in the Result.
+	// Default: false
+	Trace bool
+
+	// authenticates each request right before it's sent (e.g. with a token refreshed in the background)
+	// The requests that can't be authenticated aren't sent and fail with an [AuthError].
+	// Default: nil (no authentication)
+	Auth Authenticator
+
+	// callbacks invoked at the events of the run (e.g. to add headers to the requests)
+	// Default: no hooks
+	Hooks Hooks
 }
 
 // returns [Options] with defaults.
@@ -39,6 +112,26 @@ func withDefaults(op Options) Options {
 		op.RPS = 0
 	}
 
+	if op.Warmup < 0 {
+		op.Warmup = 0
+	}
+
+	if op.WarmupDuration < 0 {
+		op.WarmupDuration = 0
+	}
+
+	if op.Grace < 0 {
+		op.Grace = 0
+	}
+
+	if op.Buffer < 0 {
+		op.Buffer = 0
+	}
+
+	if op.ReorderBuffer <= 0 {
+		op.ReorderBuffer = 4 * op.Concurrency
+	}
+
 	if op.Send == nil {
 
 		// define a custom the http client to maintain a TCP connection pool
@@ -56,10 +149,17 @@ func withDefaults(op Options) Options {
 		}
 
 		// a closure that wraps the hit.Send function with a default http client
+		afterResponse := op.Hooks.AfterResponse
 		op.Send = func(req *http.Request) Result {
-			return Send(client, req)
+			return SendWithHook(client, req, afterResponse)
 		}
 	}
 
+	// wrap the send function once (the middleware is applied to the default send function as well)
+	if len(op.Middleware) > 0 {
+		op.Send = Chain(op.Send, op.Middleware...)
+		op.Middleware = nil
+	}
+
 	return op
 }
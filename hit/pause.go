@@ -0,0 +1,87 @@
+package hit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pauser pauses and resumes the requests of a run (see [Options]).
+// While paused, the dispatch workers finish their in-flight requests but don't send new ones.
+// The zero value is a running (i.e. not paused) Pauser. It is safe for concurrent use.
+type Pauser struct {
+	mu       sync.Mutex
+	resume   chan struct{} // closed when resumed (nil while running)
+	pausedAt time.Time     // start of the current pause
+	paused   time.Duration // total time paused (until the current pause)
+}
+
+// Pause pauses the requests (it does nothing if already paused).
+func (p *Pauser) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.resume == nil {
+		p.resume = make(chan struct{})
+		p.pausedAt = time.Now()
+	}
+}
+
+// Resume resumes the requests (it does nothing if not paused).
+func (p *Pauser) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.resume != nil {
+		close(p.resume) // unblocks all the waiting workers at once
+		p.resume = nil
+		p.paused += time.Since(p.pausedAt)
+	}
+}
+
+// Paused reports whether the requests are paused.
+func (p *Pauser) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.resume != nil
+}
+
+// pausedFor returns the total time paused so far (including the current pause).
+// It is safe to call on a nil Pauser (it never pauses).
+func (p *Pauser) pausedFor() time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.resume != nil {
+		return p.paused + time.Since(p.pausedAt)
+	}
+	return p.paused
+}
+
+// wait blocks while paused or until ctx is done.
+// It is safe to call on a nil Pauser (it never pauses).
+func (p *Pauser) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	resume := p.resume
+	p.mu.Unlock()
+
+	if resume == nil {
+		return nil
+	}
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
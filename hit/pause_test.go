@@ -0,0 +1,119 @@
+package hit
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// test that no requests are sent while the run is paused
+func TestSendNPaused(t *testing.T) {
+	const N int = 10
+
+	synctest.Test(t, func(t *testing.T) {
+		var sent atomic.Int64
+
+		opts := Options{Concurrency: 2, Pauser: &Pauser{}}
+		opts.Send = func(_ *http.Request) Result {
+			sent.Add(1)
+			return Result{Status: http.StatusOK}
+		}
+
+		opts.Pauser.Pause()
+
+		results, err := SendN(context.Background(), N, opts, getTestHttpRequest())
+		if err != nil {
+			t.Fatalf("SendN() = %v; want no error\n", err)
+		}
+
+		// consume the results in the background
+		done := make(chan Summary)
+		go func() {
+			done <- Summarize(results)
+		}()
+
+		time.Sleep(time.Minute)
+		synctest.Wait() // wait until all the goroutines are blocked (i.e. paused)
+
+		if got := sent.Load(); got != 0 {
+			t.Fatalf("sent %d requests while paused; want 0\n", got)
+		}
+		if !opts.Pauser.Paused() {
+			t.Errorf("Paused() = false; want true")
+		}
+
+		opts.Pauser.Resume()
+
+		if s := <-done; s.Requests != N {
+			t.Errorf("Requests: got = %d, want = %d\n", s.Requests, N)
+		}
+	})
+}
+
+// test that a paused run can still be cancelled
+func TestSendNPausedCancel(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		opts := Options{Pauser: &Pauser{}}
+		opts.Send = func(_ *http.Request) Result {
+			return Result{Status: http.StatusOK}
+		}
+		opts.Pauser.Pause()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		results, err := SendN(ctx, 10, opts, getTestHttpRequest())
+		if err != nil {
+			t.Fatalf("SendN() = %v; want no error\n", err)
+		}
+
+		if s := Summarize(results); s.Requests != 0 {
+			t.Errorf("Requests: got = %d, want = %d\n", s.Requests, 0)
+		}
+	})
+}
+
+// test that the warm-up duration is measured from the first request sent and excludes the pauses
+func TestSendNWarmupDurationPaused(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		// a request every 100ms during a 1s warm-up
+		opts := Options{RPS: 10, WarmupDuration: time.Second, Pauser: &Pauser{}}
+		opts.Send = func(_ *http.Request) Result {
+			return Result{Status: http.StatusOK, Start: time.Now()}
+		}
+
+		// paused for 2s before the first request and for 3s half way through the warm-up
+		opts.Pauser.Pause()
+		go func() {
+			time.Sleep(2 * time.Second)
+			opts.Pauser.Resume()
+			time.Sleep(500 * time.Millisecond)
+			opts.Pauser.Pause()
+			time.Sleep(3 * time.Second)
+			opts.Pauser.Resume()
+		}()
+
+		start := time.Now()
+		results, err := SendN(context.Background(), 20, opts, getTestHttpRequest())
+		if err != nil {
+			t.Fatalf("SendN() = %v; want no error\n", err)
+		}
+
+		// the warm-up ends after 0.5s before and 0.5s after the second pause, i.e. at 6s
+		// (the first measured request may be sent up to 2 intervals later as the producer and the throttle hold a request each)
+		var first time.Duration
+		for r := range results {
+			if !r.Warmup && first == 0 {
+				first = r.Start.Sub(start)
+			}
+		}
+		if first < 6*time.Second || first > 6200*time.Millisecond {
+			t.Errorf("first measured request: got = %s, want = 6s (up to 6.2s)\n", first)
+		}
+	})
+}
@@ -1,54 +1,196 @@
 // This file defines a concurrent pipeline to send N requests to a server concurrently
 // consists of 3 stages: a Producer, Throttler and a Dispatcher
+// (and a Reorderer to deliver the results in order, see [Options])
 // each stage returns a receive-only channel to deliver its output
+// (the stages are built with the generic stages of the pipeline package)
 
 package hit
 
 import (
 	"context"
+	"errors"
+	"io"
+	"maps"
 	"net/http"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/faizan2786/gobyexample/pipeline"
 )
 
-func runPipeline(ctx context.Context, n int, opts Options, req *http.Request) <-chan Result {
+// job is a single request travelling through the pipeline.
+// (the actual http request is built by the dispatcher right before sending it
+// so that its template can be rendered with the worker's ID)
+type job struct {
+	seq    int     // sequence number of the request
+	target *Target // target picked for the request
+	data   Record  // record of the data feeder (if any)
+	err    error   // error that prevents the request from being sent (if any)
+	warmup bool    // the request is sent in the warm-up phase
+
+	offset time.Duration // offset from the first request of a replayed log
+	at     time.Time     // time the request is scheduled at (zero if not scheduled)
+}
+
+// run holds the contexts of a pipeline run.
+//
+// A cancelled run stops producing new requests right away,
+// but gives the in-flight requests a grace period to finish (and still delivers their results).
+// Whereas a consumer that stops reading the results stops everything right away.
+type run struct {
+	ctx  context.Context    // cancelled when the run is cancelled or the consumer stops (stops producing the requests)
+	send context.Context    // context of the requests (cancelled after the grace period of a cancelled run)
+	done context.Context    // cancelled when the consumer stops (stops delivering the results)
+	stop context.CancelFunc // stops the whole run (must be called when the consumer stops)
+
+	hooks Hooks // event hooks of the run
+
+	reportSkipped bool // deliver a Skipped result for each request that was never sent (see Options)
+
+	// the producer counts the (measured) requests it produced
+	// and flags the run as exhausted once it has produced all of them (e.g. the end of a log)
+	produced  atomic.Int64
+	exhausted atomic.Bool
+
+	warmup warmupClock // measures the warm-up duration (see Options)
+}
+
+// warmupClock measures the warm-up duration from the first request dispatched (excluding the pauses).
+type warmupClock struct {
+	pauser *Pauser
+	base   time.Time
+	start  atomic.Int64 // active time of the first dispatch + 1 (0 until the first dispatch)
+}
+
+// active returns the time since base the run wasn't paused.
+func (c *warmupClock) active() time.Duration {
+	return time.Since(c.base) - c.pauser.pausedFor()
+}
+
+// dispatched starts the clock with the first request dispatched.
+func (c *warmupClock) dispatched() {
+	if c.start.Load() == 0 {
+		c.start.CompareAndSwap(0, int64(c.active())+1)
+	}
+}
+
+// elapsed returns the duration of the warm-up so far (0 until the first request is dispatched).
+func (c *warmupClock) elapsed() time.Duration {
+	start := c.start.Load()
+	if start == 0 {
+		return 0
+	}
+	return c.active() - time.Duration(start-1)
+}
+
+// newRun starts a run (and the client monitor of opts if any, which is stopped with the run).
+// The OnStart and OnEnd hooks are called when the run starts and stops.
+func newRun(parent context.Context, opts Options) *run {
+	if f := opts.Hooks.OnStart; f != nil {
+		f()
+	}
+
+	// the results are delivered (even after the parent is cancelled) until the consumer stops
+	done, stop := context.WithCancel(context.WithoutCancel(parent))
+
+	ctx, cancel := context.WithCancel(parent)
+	context.AfterFunc(done, cancel)
+
+	// the in-flight requests are cancelled at the end of the grace period
+	// (the timer is stopped if the run ends first)
+	send, cancelSend := context.WithCancel(done)
+	context.AfterFunc(ctx, func() {
+		t := time.AfterFunc(opts.Grace, cancelSend)
+		context.AfterFunc(done, func() { t.Stop() })
+	})
+
+	stopMonitor := opts.Monitor.start(opts)
+	stopRun := sync.OnceFunc(func() {
+		cause := context.Cause(ctx) // (before stop cancels it)
+		stop()
+		stopMonitor() // (waits for the last sample)
+		if f := opts.Hooks.OnEnd; f != nil {
+			f(cause)
+		}
+	})
+
+	rn := &run{ctx: ctx, send: send, done: done, stop: stopRun, hooks: opts.Hooks, reportSkipped: opts.ReportSkipped}
+	rn.warmup.pauser, rn.warmup.base = opts.Pauser, time.Now()
+	return rn
+}
 
-	requests := produce(ctx, n, req) // stage-1
+// runPipeline throttles and dispatches the requests from a producer (stage-1).
+func runPipeline(rn *run, opts Options, requests <-chan job) <-chan Result {
 
 	// throttle if RPS is given
 	if opts.RPS > 0 {
-		requests = throttle(ctx, opts.RPS, requests) // stage-2
+		requests = throttle(rn.ctx, opts.RPS, requests) // stage-2
 	}
 
-	return dispatch(ctx, opts, requests)
+	return dispatchAll(rn, opts, requests)
 }
 
-// produces [http.Request]s.
-func produce(ctx context.Context, n int, req *http.Request) <-chan *http.Request {
-	// step-1: make an output channel
-	out := make(chan *http.Request)
+// dispatchAll dispatches the requests and delivers their results in order if required.
+func dispatchAll(rn *run, opts Options, requests <-chan job) <-chan Result {
+	if !opts.Ordered {
+		return dispatch(rn, opts, requests)
+	}
 
-	// step-2: spawn worker go routine(s) that writes to the output channel
-	go func() {
-		defer close(out) // it is IMP to close the channel before we return (to unblock any receiver)
+	// each request holds a slot of the window from the time it's dispatched until its result is delivered
+	// (so that the reorder buffer never holds more results than the window size)
+	window := make(chan struct{}, opts.ReorderBuffer)
+	return reorder(rn, window, dispatch(rn, opts, admit(rn.ctx, window, requests)))
+}
 
-		for range n {
-			// send or return
-			select {
-			case out <- req.Clone(ctx): // clone the request with the passed context and send to output channel
-			case <-ctx.Done():
-				return // exit goroutine if the context is cancelled (i.e. Done)
+// produces a job for each request with a target picked from the mix
+// (and a record from the feeder unless each worker uses its own record).
+// The warm-up jobs (if any) are produced before the n measured jobs.
+// It stops early if the feeder runs out of records.
+// The run is flagged as exhausted once all the jobs are produced (i.e. not cancelled).
+func produce(rn *run, n int, m *mix, opts Options) <-chan job {
+
+	jobs := func(yield func(job) bool) {
+		f := opts.Feeder
+
+		for seq, measured := 0, 0; measured < n; seq++ {
+			j := job{seq: seq, target: m.pick()}
+
+			// (the warm-up duration is measured from the first request dispatched, see dispatchJob)
+			j.warmup = seq < opts.Warmup || rn.warmup.elapsed() < opts.WarmupDuration
+			if !j.warmup {
+				measured++
+			}
+
+			if f != nil && f.Mode != FeedUnique {
+				j.data, j.err = f.Next()
+				if errors.Is(j.err, io.EOF) {
+					break // the data ran out - stop the run
+				}
 			}
 
-			// Note that, above way of putting send (out <- ...) on case statement will allow runtime to choose ctx.Done()
-			// when send is blocked and context is cancelled.
-			// This way go routine can terminate gracefully on context termination without getting stuck on send
-			// Putting send under a default: case will not achieve the same effect and will block if send is blocked.
+			if !yield(j) {
+				return
+			}
+			if !j.warmup {
+				rn.produced.Add(1)
+			}
+			if j.err != nil {
+				break // the feeder failed - stop the run (the error is reported in the job's result)
+			}
 		}
-	}()
+		rn.exhausted.Store(true)
+	}
 
-	// step-3: return output channel immediately
-	return out
+	// the jobs are produced lazily (i.e. one at a time, when the next stage is ready to receive it)
+	// or up to opts.Buffer jobs ahead in the high-throughput mode (unless they're throttled)
+	// and the producer stops when the context is cancelled
+	buffer := opts.Buffer
+	if opts.RPS > 0 {
+		buffer = 0
+	}
+	return pipeline.FromBuffered(rn.ctx, buffer, jobs)
 
 	// Note:
 	// when producer context is cancelled it closes the out channel and returns
@@ -57,61 +199,199 @@ func produce(ctx context.Context, n int, req *http.Request) <-chan *http.Request
 
 	// However, this will not cancel the ongoing operations in the consumers (e.g., if they happened to be blocked on send).
 	// Hence, to terminate each of the component of the pipeline gracefully on parent's context cancel,
-	// we must pass the context to each component and use select...case in each component
-}
-
-func throttle(ctx context.Context, rps int, in <-chan *http.Request) <-chan *http.Request {
-	out := make(chan *http.Request)
-
-	if rps > 0 {
-		interval := time.Second / time.Duration(rps) // time interval between each tick (i.e. request)
-		go func() {
-			defer close(out)
-			t := time.NewTicker(interval)
-			for req := range in {
-				select {
-				case <-t.C: // wait until next tick
-					// send or return
-					select {
-					case out <- req:
-					case <-ctx.Done():
-						return
-					}
-				case <-ctx.Done():
-					return
-				}
+	// we must pass the context to each component (see the pipeline package)
+}
+
+func throttle(ctx context.Context, rps int, in <-chan job) <-chan job {
+	interval := time.Second / time.Duration(rps) // time interval between each tick (i.e. request)
+	return pipeline.RateLimit[job](interval)(ctx, in)
+}
+
+// dispatch sends the requests with opts.Concurrency workers.
+// The workers stop delivering the results only when the consumer stops (i.e. rn.done),
+// so that the results of the in-flight requests of a cancelled run are still delivered.
+func dispatch(rn *run, opts Options, in <-chan job) <-chan Result {
+	return pipeline.WorkersBuffered(opts.Concurrency, opts.Buffer, func(_ context.Context, worker int, in <-chan job, emit func(Result) bool) {
+		data, err := workerData(opts)
+		if errors.Is(err, io.EOF) {
+			return // no record left for this worker (its jobs are taken by the other workers)
+		}
+
+		// read the jobs, build their requests, invoke Send() and deliver the results
+		for j := range in {
+			// the feeder failed - the error is reported in the result of the worker's next job
+			// and the worker stops (the same way the producer reports a failed feeder)
+			if err != nil {
+				j.err = err
+			}
+
+			r, ok := dispatchJob(rn, opts, j, worker, data)
+			if !ok {
+				continue
 			}
-		}()
+
+			// deliver or return
+			// (the result is delivered even if the run is cancelled - unless the consumer stops)
+			if !emit(r) || err != nil {
+				return
+			}
+		}
+	})(rn.done, in)
+}
+
+// workerData returns the record of a worker in unique mode
+// (i.e. a virtual user uses its own record for all of its requests).
+// It returns [io.EOF] if there's no record left for the worker
+// (and the error of the feeder if it fails, e.g. a malformed record).
+func workerData(opts Options) (Record, error) {
+	if opts.Feeder == nil || opts.Feeder.Mode != FeedUnique {
+		return nil, nil
+	}
+	return opts.Feeder.Next()
+}
+
+// dispatchJob sends the request of a job by a worker.
+// It returns false if the request isn't sent because the run is cancelled.
+func dispatchJob(rn *run, opts Options, j job, worker int, data Record) (Result, bool) {
+	if data != nil {
+		j.data = data
 	}
 
-	return out
+	// wait while the run is paused
+	_ = opts.Pauser.wait(rn.ctx)
+
+	// don't send the requests left in the pipeline after the run is cancelled
+	// (keep draining them until the producer closes the channel)
+	if rn.ctx.Err() != nil {
+		return Result{}, false
+	}
+
+	rn.warmup.dispatched()
+	r := send(rn.send, opts, j, worker)
+	opts.Monitor.observe(r)
+
+	// an in-flight request that didn't finish within the grace period
+	if r.Error != nil && rn.send.Err() != nil {
+		r.Abandoned = true
+	}
+	return r, true
+}
+
+// admit takes a slot of the window for each job (it blocks while the window is full).
+func admit(ctx context.Context, window chan<- struct{}, in <-chan job) <-chan job {
+	return pipeline.Workers(1, func(ctx context.Context, _ int, in <-chan job, emit func(job) bool) {
+		for j := range pipeline.Each(ctx, in) {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			if !emit(j) {
+				return
+			}
+		}
+	})(ctx, in)
 }
 
-func dispatch(ctx context.Context, opts Options, in <-chan *http.Request) <-chan Result {
-	out := make(chan Result)
+// reorder delivers the results in the order of their sequence numbers
+// and frees a slot of the window for each delivered result.
+// The results that arrive early are buffered until the results before them arrive.
+func reorder(rn *run, window <-chan struct{}, in <-chan Result) <-chan Result {
+	return pipeline.Workers(1, func(ctx context.Context, _ int, in <-chan Result, emit func(Result) bool) {
 
-	var wg sync.WaitGroup
-	for range opts.Concurrency {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			// read the requests, invoke Send() and send result to out channel
-			for req := range in {
-				// send or return
-				select {
-				case out <- opts.Send(req):
-				case <-ctx.Done():
+		// deliver or return
+		deliver := func(r Result) bool {
+			<-window
+			return emit(r)
+		}
+
+		pending := make(map[int]Result) // results waiting for the ones before them
+		next := 0                       // sequence number of the next result to deliver
+		for r := range pipeline.Each(ctx, in) {
+			pending[r.Seq] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !deliver(r) {
 					return
 				}
 			}
-		}()
+		}
+
+		// a cancelled run leaves gaps (for the requests that weren't sent)
+		// hence, deliver the rest of the results in order
+		for _, seq := range slices.Sorted(maps.Keys(pending)) {
+			if !deliver(pending[seq]) {
+				return
+			}
+		}
+	})(rn.done, in)
+}
+
+// send builds the job's request with the pipeline's context and sends it.
+func send(ctx context.Context, opts Options, j job, worker int) Result {
+	var r Result
+
+	// how late the request is sent compared to its schedule
+	var lag time.Duration
+	if !j.at.IsZero() {
+		lag = time.Since(j.at)
+	}
+
+	start := time.Now()
+	req, err := j.request(ctx, worker)
+	var traceID, requestID string
+	if err == nil && opts.Trace {
+		traceID, requestID = traceRequest(req)
 	}
-	// spawn a go routine to close the output channel when all workers are done
-	// (i.e. when the input channel closes)
-	go func() {
-		wg.Wait()
-		close(out)
-	}()
+	if err == nil && opts.Auth != nil {
+		if aerr := opts.Auth.Authenticate(req); aerr != nil {
+			err = &AuthError{Err: aerr}
+		}
+	}
+
+	if err != nil {
+		r = Result{Error: err} // the request couldn't be built or authenticated (hence, wasn't sent)
+	} else {
+		if f := opts.Hooks.BeforeRequest; f != nil {
+			f(req)
+		}
+		start = time.Now()
+		r = opts.Send(req)
+	}
+	if req != nil {
+		r.Method = req.Method
+		r.URL = j.target.urlOf(req)
+	} else {
+		r.Method, r.URL = j.target.describe() // (the request couldn't be built)
+	}
+	r.TraceID, r.RequestID = traceID, requestID
 
-	return out
+	// timestamps can be set by a custom Send function
+	if r.Start.IsZero() {
+		r.Start = start
+	}
+	if r.End.IsZero() {
+		r.End = time.Now()
+	}
+	r.Seq = j.seq
+	r.Worker = worker
+
+	r.Target = j.target.Name // tag the result with its target
+	r.Lag = lag
+	r.Warmup = j.warmup
+	return r
+}
+
+// request builds the job's request for the given worker.
+func (j job) request(ctx context.Context, worker int) (*http.Request, error) {
+	if j.err != nil {
+		return nil, j.err
+	}
+	return j.target.request(ctx, TemplateData{Seq: j.seq, Worker: worker, Data: j.data})
 }
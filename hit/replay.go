@@ -0,0 +1,163 @@
+// This file defines the replay mode that sends the requests of an access log
+// to a new server, keeping the original time between the requests (i.e. the traffic shape)
+// It uses the same pipeline as SendN but replaces the throttler with a scheduler
+
+package hit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/faizan2786/gobyexample/pipeline"
+)
+
+// Replay sends the requests of an access log to the server at base (e.g. "http://localhost:8082")
+// keeping their original inter-arrival times divided by speed (e.g. 2 replays twice as fast).
+//
+// Each [Result] is tagged with the request's method and path (e.g. "GET /items") as its target
+// and its Lag behind the schedule. The Concurrency option bounds the number of requests in flight
+// (a too low concurrency shows up as lag). The results are delivered in the log's order if Ordered is set.
+// A pause (see [Pauser]) shifts the rest of the schedule by its duration.
+// The RPS, Feeder and Warmup options are not used.
+// As the log is streamed, the number of its requests is unknown: if the run is cancelled (and ReportSkipped is set),
+// the results end with a single Skipped result for the rest of the log.
+func Replay(ctx context.Context, opts Options, base string, entries iter.Seq2[LogEntry, error], speed float64) (Results, error) {
+
+	opts = withDefaults(opts)
+
+	if speed <= 0 {
+		return nil, fmt.Errorf("speed must be greater than 0: got %v", speed)
+	}
+
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q: requires a valid url with a scheme and host", base)
+	}
+
+	rn := newRun(ctx, opts)
+
+	requests := schedule(rn.ctx, speed, opts.Pauser, produceLog(rn, strings.TrimSuffix(base, "/"), entries))
+	results := dispatchAll(rn, opts, requests)
+
+	// the number of planned requests is unknown as the log is streamed
+	return iterate(rn, pipeline.Values(results), -1), nil
+}
+
+// produces a job for each entry of the access log with its offset from the first entry.
+// It stops at the first error of the log (the error is reported in the job's result).
+// The run is flagged as exhausted once all the entries are produced (i.e. not cancelled).
+func produceLog(rn *run, base string, entries iter.Seq2[LogEntry, error]) <-chan job {
+
+	jobs := func(yield func(job) bool) {
+		var (
+			seq   int
+			first time.Time
+		)
+		for e, err := range entries {
+			j := job{seq: seq, target: &Target{}}
+			seq++
+
+			if err == nil {
+				if first.IsZero() {
+					first = e.Time
+				}
+				j.offset = e.Time.Sub(first)
+				j.target, err = logTarget(base, e)
+			}
+			j.err = err
+
+			if !yield(j) {
+				return
+			}
+			rn.produced.Add(1)
+			if j.err != nil {
+				break
+			}
+		}
+		rn.exhausted.Store(true)
+	}
+
+	return pipeline.From(rn.ctx, jobs)
+}
+
+// logTarget returns a target with the request of a log entry sent to base.
+func logTarget(base string, e LogEntry) (*Target, error) {
+
+	// keep only the path and query of an absolute url (e.g. recorded by a proxy)
+	path := e.Path
+	if u, err := url.Parse(path); err == nil && u.IsAbs() {
+		path = u.RequestURI()
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	var body io.Reader = http.NoBody
+	switch {
+	case len(e.RawBody) > 0:
+		body = bytes.NewReader(e.RawBody)
+	case e.Body != "":
+		body = strings.NewReader(e.Body)
+	}
+
+	req, err := http.NewRequest(e.Method, base+path, body)
+	if err != nil {
+		return &Target{}, fmt.Errorf("creating request for %s %s: %w", e.Method, e.Path, err)
+	}
+	for key, values := range e.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+
+	return &Target{Name: requestName(e.Method, path), Request: req}, nil
+}
+
+// schedule delivers each job at its offset (divided by speed) from the time the first job arrives
+// shifted by the time paused (so that resuming doesn't send the requests due during the pause in a burst).
+func schedule(ctx context.Context, speed float64, pauser *Pauser, in <-chan job) <-chan job {
+
+	// a single worker keeps the jobs in the order of the log
+	return pipeline.Workers(1, func(ctx context.Context, _ int, in <-chan job, emit func(job) bool) {
+		var start time.Time
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for j := range in {
+			if start.IsZero() {
+				start = time.Now()
+			}
+			offset := time.Duration(float64(j.offset) / speed)
+
+			// wait until the scheduled time
+			// (again if the run was paused in the meantime, as the pause shifts the schedule)
+			for {
+				if pauser.wait(ctx) != nil {
+					return
+				}
+				j.at = start.Add(offset + pauser.pausedFor())
+				if !time.Now().Before(j.at) {
+					break
+				}
+
+				timer.Reset(time.Until(j.at))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if !emit(j) {
+				return
+			}
+		}
+	})(ctx, in)
+}
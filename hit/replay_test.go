@@ -0,0 +1,250 @@
+package hit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+const testCLF = `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /items?page=1 HTTP/1.0" 200 2326
+127.0.0.1 - - [10/Oct/2000:13:55:46 -0700] "-" 408 0
+127.0.0.1 - - [10/Oct/2000:13:55:46 -0700] "POST /items HTTP/1.1" 201 12 "http://example.com/" "Mozilla/5.0"
+127.0.0.1 - - [10/Oct/2000:13:56:06 -0700] "GET /items?page=2 HTTP/1.1" 200 2326 "-" "curl/8.0"
+`
+
+func TestReadAccessLog(t *testing.T) {
+
+	var got []LogEntry
+	for e, err := range ReadAccessLog(strings.NewReader(testCLF), LogCommon) {
+		if err != nil {
+			t.Fatalf("ReadAccessLog() = %v; want no error\n", err)
+		}
+		got = append(got, e)
+	}
+
+	// the line without a request is skipped
+	want := []string{"GET /items?page=1", "POST /items", "GET /items?page=2"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries; want %d\n", len(got), len(want))
+	}
+	for i, e := range got {
+		if e.Method+" "+e.Path != want[i] {
+			t.Errorf("entry %d: got = %s %s, want = %s\n", i, e.Method, e.Path, want[i])
+		}
+	}
+
+	if d := got[2].Time.Sub(got[0].Time); d != 30*time.Second {
+		t.Errorf("time between first and last entry: got = %v, want = %v\n", d, 30*time.Second)
+	}
+}
+
+func TestReadAccessLogInvalid(t *testing.T) {
+
+	log := "{\"time\": \"2000-10-10T13:55:36Z\", \"path\": \"/items\"}\nnot json\n"
+
+	var (
+		entries int
+		errs    int
+	)
+	for _, err := range ReadAccessLog(strings.NewReader(log), LogJSONL) {
+		if err != nil {
+			errs++
+			continue
+		}
+		entries++
+	}
+
+	if entries != 1 || errs != 1 {
+		t.Errorf("got %d entries and %d errors; want 1 entry and 1 error\n", entries, errs)
+	}
+}
+
+// test that the requests are replayed with the original timing (scaled by speed)
+func TestReplay(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		var (
+			mu    sync.Mutex
+			sent  []time.Duration // time each request was sent (since the start of the replay)
+			urls  []string
+			start = time.Now()
+		)
+
+		opts := Options{Concurrency: 2}
+		opts.Send = func(req *http.Request) Result {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, time.Since(start))
+			urls = append(urls, req.URL.String())
+			return Result{Status: http.StatusOK}
+		}
+
+		results, err := Replay(context.Background(), opts, "http://localhost:8082/", ReadAccessLog(strings.NewReader(testCLF), LogCommon), 2)
+		if err != nil {
+			t.Fatalf("Replay() = %v; want no error\n", err)
+		}
+
+		s := Summarize(results)
+
+		// original offsets are 0s, 10s and 30s (replayed twice as fast)
+		want := []time.Duration{0, 5 * time.Second, 15 * time.Second}
+		if !slices.Equal(sent, want) {
+			t.Errorf("sent at: got = %v, want = %v\n", sent, want)
+		}
+
+		wantURLs := []string{"http://localhost:8082/items?page=1", "http://localhost:8082/items", "http://localhost:8082/items?page=2"}
+		if !slices.Equal(urls, wantURLs) {
+			t.Errorf("urls: got = %v, want = %v\n", urls, wantURLs)
+		}
+
+		// per path statistics
+		if got := s.Targets["GET /items"].Requests; got != 2 {
+			t.Errorf("GET /items requests: got = %d, want = %d\n", got, 2)
+		}
+		if got := s.Targets["POST /items"].Requests; got != 1 {
+			t.Errorf("POST /items requests: got = %d, want = %d\n", got, 1)
+		}
+
+		if s.MaxLag != 0 {
+			t.Errorf("MaxLag: got = %v, want = %v\n", s.MaxLag, 0)
+		}
+	})
+}
+
+// test that a pause shifts the rest of the schedule (instead of sending the requests due during the pause at once)
+func TestReplayPaused(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		var (
+			mu    sync.Mutex
+			sent  []time.Duration // time each request was sent (since the start of the replay)
+			start = time.Now()
+		)
+
+		opts := Options{Concurrency: 2, Pauser: &Pauser{}}
+		opts.Send = func(req *http.Request) Result {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, time.Since(start))
+			return Result{Status: http.StatusOK}
+		}
+
+		// paused from 2s to 10s (i.e. while the request at 5s is due)
+		go func() {
+			time.Sleep(2 * time.Second)
+			opts.Pauser.Pause()
+			time.Sleep(8 * time.Second)
+			opts.Pauser.Resume()
+		}()
+
+		results, err := Replay(context.Background(), opts, "http://localhost:8082/", ReadAccessLog(strings.NewReader(testCLF), LogCommon), 2)
+		if err != nil {
+			t.Fatalf("Replay() = %v; want no error\n", err)
+		}
+
+		s := Summarize(results)
+
+		// original offsets are 0s, 5s and 15s (replayed twice as fast) shifted by 8s after the pause
+		want := []time.Duration{0, 13 * time.Second, 23 * time.Second}
+		if !slices.Equal(sent, want) {
+			t.Errorf("sent at: got = %v, want = %v\n", sent, want)
+		}
+
+		if s.MaxLag != 0 {
+			t.Errorf("MaxLag: got = %v, want = %v\n", s.MaxLag, 0)
+		}
+	})
+}
+
+// test that a base64 raw body (e.g. a binary body recorded by cmd/hitrecord) is replayed unchanged
+func TestReplayRawBody(t *testing.T) {
+
+	log := `{"time": "2000-10-10T13:55:36Z", "method": "POST", "path": "/upload", "raw_body": "/wCAaGk="}` + "\n"
+
+	var got []byte
+	opts := Options{}
+	opts.Send = func(req *http.Request) Result {
+		got, _ = io.ReadAll(req.Body)
+		return Result{Status: http.StatusOK}
+	}
+
+	results, err := Replay(context.Background(), opts, "http://localhost:8082", ReadAccessLog(strings.NewReader(log), LogJSONL), 1)
+	if err != nil {
+		t.Fatalf("Replay() = %v; want no error\n", err)
+	}
+	Summarize(results)
+
+	want := []byte{0xff, 0x00, 0x80, 'h', 'i'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("body: got = %v, want = %v\n", got, want)
+	}
+}
+
+// test that a cancelled replay is reported as partial with its cause
+// (even if none of its requests is abandoned, as the rest of the log is skipped)
+func TestReplayCancelled(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		opts := Options{ReportSkipped: true}
+		opts.Send = func(_ *http.Request) Result {
+			return Result{Status: http.StatusOK}
+		}
+
+		// the log spans 30s, the replay is cancelled after 15s
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		results, err := Replay(ctx, opts, "http://localhost:8082", ReadAccessLog(strings.NewReader(testCLF), LogCommon), 1)
+		if err != nil {
+			t.Fatalf("Replay() = %v; want no error\n", err)
+		}
+
+		s := Summarize(results)
+		if !s.Partial || !errors.Is(s.Cause, context.DeadlineExceeded) {
+			t.Errorf("Partial, Cause: got = %v, %v, want = true, %v\n", s.Partial, s.Cause, context.DeadlineExceeded)
+		}
+		if s.Requests != 2 || s.Abandoned != 0 {
+			t.Errorf("Requests, Abandoned: got = %d, %d, want = %d, %d\n", s.Requests, s.Abandoned, 2, 0)
+		}
+	})
+}
+
+// test that a replay cancelled once the whole log is sent isn't partial
+func TestReplayCancelledAfterLog(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		opts := Options{ReportSkipped: true}
+		opts.Send = func(_ *http.Request) Result {
+			return Result{Status: http.StatusOK}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		results, err := Replay(ctx, opts, "http://localhost:8082", ReadAccessLog(strings.NewReader(testCLF), LogCommon), 1)
+		if err != nil {
+			t.Fatalf("Replay() = %v; want no error\n", err)
+		}
+
+		n := 0
+		sz := NewSummarizer()
+		for r := range results {
+			sz.Add(r)
+			if n++; n == 3 {
+				cancel() // after the last request of the log
+			}
+		}
+
+		if s := sz.Summary(); s.Partial || s.Requests != 3 {
+			t.Errorf("Partial, Requests: got = %v, %d, want = false, %d\n", s.Partial, s.Requests, 3)
+		}
+	})
+}
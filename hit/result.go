@@ -5,6 +5,8 @@ package hit
 
 import (
 	"iter"
+	"maps"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,6 +16,25 @@ type Result struct {
 	Bytes    int64         // Number of bytes received
 	Duration time.Duration // Duration to complete a request
 	Error    error
+	Target   string        // Target is the name of the [Target] the request was sent to
+	Lag      time.Duration // Lag is how late the request was sent compared to its schedule (see [Replay])
+	Warmup   bool          // Warmup is true if the request was sent in the warm-up phase (see [Options])
+
+	// metadata to correlate the result with the server's logs
+	Seq    int       // Seq is the sequence number of the request in the run
+	Worker int       // Worker is the ID of the dispatch worker that sent the request
+	Start  time.Time // Start is the time the request was sent
+	End    time.Time // End is the time the response was received (or the request failed)
+	Method string    // Method is the http method of the request
+	URL    string    // URL is the url of the request (its template if it couldn't be rendered)
+
+	// the IDs to find the request in the server's traces and logs (see the Trace option)
+	TraceID   string // TraceID is the W3C trace ID of the request (empty if not traced)
+	RequestID string // RequestID is the X-Request-ID of the request (empty if not traced)
+
+	// a cancelled run doesn't complete all of its requests
+	Skipped   bool // Skipped is true if the request was never sent as the run was cancelled
+	Abandoned bool // Abandoned is true if the request was in flight when the grace period of a cancelled run ended
 }
 
 // Results is an iterator for a collection of [Result] values.
@@ -27,9 +48,57 @@ type Summary struct {
 	Fastest  time.Duration // Fastest is the fastest request duration
 	Slowest  time.Duration // Slowest is the slowest request duration
 	Average  time.Duration // Average request duration - average response time for an individual request (i.e. Latency)
-	Duration time.Duration // Duration is the total (clock) time taken by all the requests
+	Duration time.Duration // Duration is the total (clock) time taken by all the requests (from the first start to the last end)
 	RPS      float64       // RPS is the number of requests served per second (i.e. Throughput)
 	Success  float64       // Success is the ratio of successful requests
+
+	// Histogram is the distribution of the request durations (see [Summary.Percentile])
+	Histogram Histogram
+
+	// Statuses is the number of responses of each status code
+	// (0 counts the failed requests without a response, e.g. a connection error)
+	Statuses map[int]int
+
+	AverageLag time.Duration // AverageLag is the average lag of the requests behind their schedule (see [Replay])
+	MaxLag     time.Duration // MaxLag is the maximum lag of a request behind its schedule (i.e. drift from the original schedule)
+
+	// Targets breaks the summary down per named [Target]
+	// (nil if none of the results belongs to a named target)
+	Targets map[string]Summary
+
+	// Warmup summarizes the warm-up results (nil if there was no warm-up)
+	// They are excluded from the rest of the summary.
+	Warmup *Summary
+
+	// A cancelled run is partial: the summary only covers the requests completed before the cancellation
+	// (Requests is the number of completed requests)
+	Partial   bool  // Partial is true if some of the planned requests were skipped or abandoned
+	Planned   int   // Planned is the number of requests planned to be sent (if known)
+	Sent      int   // Sent is the number of requests sent (completed or abandoned)
+	Abandoned int   // Abandoned is the number of in-flight requests abandoned at the end of the grace period
+	Cause     error // Cause is the cancellation cause of a partial run
+
+	// AuthErrors is the number of requests that couldn't be authenticated (see [AuthError])
+	// They were never sent, hence, they're excluded from the rest of the summary
+	// (so that an auth failure isn't mistaken for a failure of the target).
+	AuthErrors int
+
+	// SlowestResults are the slowest (measured) results, slowest first (at most 10)
+	// e.g. to look up their RequestID and TraceID in the server's logs and traces
+	SlowestResults []Result
+
+	// ErrorSamples are the first distinct error messages of the (measured) results, first seen first (at most 10)
+	// with their counts (the errors of the other messages are only counted in Errors)
+	ErrorSamples []ErrorSample
+}
+
+// Percentile returns the p-th percentile (0 to 100) of the request durations
+// (e.g. 99 for the p99 latency) within about 3% of the exact value.
+func (s Summary) Percentile(p float64) time.Duration {
+	if p <= 0 {
+		return s.Fastest
+	}
+	return min(max(s.Histogram.Percentile(p), s.Fastest), s.Slowest)
 }
 
 // Summarize returns a [Summary] of [Results].
@@ -41,33 +110,291 @@ func Summarize(results Results) Summary {
 		return s // return a zero-value summary
 	}
 
-	var requestDurationSum time.Duration // sum of all request durations
-
-	start := time.Now()
+	sz := NewSummarizer()
 	for r := range results {
-		s.Requests += 1
-		s.Bytes += r.Bytes
+		sz.Add(r)
+	}
+	return sz.Summary()
+}
+
+// Summarizer builds a [Summary] incrementally from [Result] values.
+// It is safe for concurrent use
+// (e.g. to print a partial summary while the results are still being added).
+//
+// The aggregation is lock-free: the counters are updated with atomic operations
+// so that concurrent calls to Add (e.g. one per worker) never wait for each other.
+// Hence, a summary taken while the results are being added may be off by the results in the middle of being added.
+type Summarizer struct {
+	total  stats // stats of all the (measured) results
+	warmup stats // stats of the warm-up results
+
+	slowest slowest      // slowest (measured) results
+	failed  errorSamples // distinct errors of the (measured) results
+
+	// stats of the results of each named target
+	// (the map is copied on write as the targets are added only once)
+	targets atomic.Pointer[map[string]*stats]
+
+	created time.Time // used as the clock for the results without timestamps
+
+	skipped, abandoned atomic.Int64 // requests of a cancelled run that were never sent or never completed
+	authErrors         atomic.Int64 // requests that couldn't be authenticated (never sent)
+
+	// cancellation cause of the run (if cancelled)
+	// the cause of the skipped requests (i.e. of the run) is preferred over the error of an abandoned request
+	skipCause, abandonCause atomic.Pointer[error]
+}
+
+// NewSummarizer returns a new [Summarizer].
+// The clock time of the summary is measured from the first start to the last end of the results
+// (or since the summarizer is created for the results without timestamps).
+func NewSummarizer() *Summarizer {
+	return &Summarizer{
+		created: time.Now(),
+		slowest: slowest{k: sampleSize},
+		failed:  errorSamples{k: sampleSize},
+	}
+}
+
+// Add adds a [Result] to the summary.
+func (sz *Summarizer) Add(r Result) {
 
-		if r.Error != nil {
-			s.Errors += 1
+	// the skipped and abandoned requests of a cancelled run are only counted
+	if r.Skipped || r.Abandoned {
+		cause := &sz.abandonCause
+		if r.Skipped {
+			sz.skipped.Add(1)
+			cause = &sz.skipCause
+		} else {
+			sz.abandoned.Add(1)
+		}
+		if err := r.Error; err != nil {
+			cause.CompareAndSwap(nil, &err) // the first cause wins
 		}
+		return
+	}
+
+	if isAuthError(r.Error) {
+		sz.authErrors.Add(1)
+		return
+	}
+
+	if r.Warmup {
+		sz.warmup.add(r)
+		return
+	}
+
+	sz.total.add(r)
+	sz.slowest.add(r)
+	if r.Error != nil {
+		sz.failed.add(r)
+	}
+
+	if r.Target == "" {
+		return
+	}
+	sz.target(r.Target).add(r)
+}
 
-		if s.Fastest == 0 || r.Duration < s.Fastest {
-			s.Fastest = r.Duration
+// target returns the stats of a named target (adding them if it's a new target).
+func (sz *Summarizer) target(name string) *stats {
+	for {
+		old := sz.targets.Load()
+		if old != nil {
+			if t, ok := (*old)[name]; ok {
+				return t
+			}
 		}
 
-		if r.Duration > s.Slowest {
-			s.Slowest = r.Duration
+		// copy the map with the new target
+		// (and try again if another goroutine has changed it in between)
+		targets := make(map[string]*stats, 1)
+		if old != nil {
+			targets = maps.Clone(*old)
+		}
+		t := &stats{}
+		targets[name] = t
+		if sz.targets.CompareAndSwap(old, &targets) {
+			return t
 		}
+	}
+}
+
+// Summary returns the [Summary] of the results added so far.
+func (sz *Summarizer) Summary() Summary {
+	elapsed := sz.total.elapsed(sz.created) // total clock time
+
+	s := sz.total.summary(elapsed)
 
-		requestDurationSum += r.Duration
+	skipped, abandoned := int(sz.skipped.Load()), int(sz.abandoned.Load())
+	s.Abandoned = abandoned
+	s.AuthErrors = int(sz.authErrors.Load())
+	s.Sent = s.Requests + abandoned
+	s.Planned = s.Sent + skipped + s.AuthErrors
+	if skipped > 0 || abandoned > 0 {
+		s.Partial = true
+		if cause := sz.skipCause.Load(); cause != nil {
+			s.Cause = *cause
+		} else if cause := sz.abandonCause.Load(); cause != nil {
+			s.Cause = *cause
+		}
 	}
 
-	s.Duration = time.Since(start)                     // total clock time
-	s.RPS = float64(s.Requests) / s.Duration.Seconds() // throughput
+	s.SlowestResults = sz.slowest.list()
+	s.ErrorSamples = sz.failed.list()
+
+	if sz.warmup.requests.Load() > 0 {
+		w := sz.warmup.summary(sz.warmup.elapsed(sz.created))
+		s.Warmup = &w
+	}
+
+	// all the targets share the same clock time
+	// (so that per target RPS adds up to the total RPS)
+	if targets := sz.targets.Load(); targets != nil {
+		s.Targets = make(map[string]Summary, len(*targets))
+		for name, t := range *targets {
+			s.Targets[name] = t.summary(elapsed)
+		}
+	}
+
+	return s
+}
+
+// stats accumulates the [Result] values of a [Summary] (with atomic operations).
+type stats struct {
+	requests, errors    atomic.Int64
+	bytes               atomic.Int64
+	fastest, slowest    atomic.Int64    // request durations
+	requestDurationSum  atomic.Int64    // sum of all request durations
+	lagSum, maxLag      atomic.Int64    // sum and maximum of all schedule lags
+	firstStart, lastEnd atomic.Int64    // first start and last end of the results (in unix nanoseconds, 0 if unknown)
+	durations           histogram       // distribution of the request durations
+	statuses            counterMap[int] // number of responses of each status code (0 for no response)
+}
+
+func (st *stats) add(r Result) {
+	st.requests.Add(1)
+	st.bytes.Add(r.Bytes)
+
+	if r.Error != nil {
+		st.errors.Add(1)
+	}
+
+	storeMin(&st.fastest, int64(r.Duration))
+	storeMax(&st.slowest, int64(r.Duration))
+	st.requestDurationSum.Add(int64(r.Duration))
+	st.durations.add(r.Duration)
+	st.statuses.add(r.Status)
+
+	if !r.Start.IsZero() {
+		storeMin(&st.firstStart, r.Start.UnixNano())
+	}
+	if !r.End.IsZero() {
+		storeMax(&st.lastEnd, r.End.UnixNano())
+	}
+
+	st.lagSum.Add(int64(r.Lag))
+	storeMax(&st.maxLag, int64(r.Lag))
+}
+
+// counterMap are atomic counters keyed by a sparse key (e.g. the status codes of the responses)
+// The map is copied on write as the keys are added only once.
+type counterMap[K comparable] struct {
+	m atomic.Pointer[map[K]*atomic.Int64]
+}
+
+// add increments the counter of key k (adding it if it's a new key).
+func (c *counterMap[K]) add(k K) {
+	for {
+		old := c.m.Load()
+		if old != nil {
+			if n, ok := (*old)[k]; ok {
+				n.Add(1)
+				return
+			}
+		}
+
+		// copy the map with the new key
+		// (and try again if another goroutine has changed it in between)
+		m := make(map[K]*atomic.Int64, 1)
+		if old != nil {
+			m = maps.Clone(*old)
+		}
+		n := &atomic.Int64{}
+		n.Add(1)
+		m[k] = n
+		if c.m.CompareAndSwap(old, &m) {
+			return
+		}
+	}
+}
+
+// snapshot returns the current values of the counters (nil if there are none).
+func (c *counterMap[K]) snapshot() map[K]int {
+	p := c.m.Load()
+	if p == nil {
+		return nil
+	}
+	s := make(map[K]int, len(*p))
+	for k, n := range *p {
+		s[k] = int(n.Load())
+	}
+	return s
+}
+
+// storeMin stores v in a if it's lower than its value (or a is 0, i.e. unset).
+func storeMin(a *atomic.Int64, v int64) {
+	for {
+		old := a.Load()
+		if (old != 0 && v >= old) || a.CompareAndSwap(old, v) {
+			return
+		}
+		// another goroutine changed the value in between (try again)
+	}
+}
+
+// storeMax stores v in a if it's greater than its value.
+func storeMax(a *atomic.Int64, v int64) {
+	for {
+		old := a.Load()
+		if v <= old || a.CompareAndSwap(old, v) {
+			return
+		}
+	}
+}
+
+// elapsed returns the clock time from the first start to the last end of the results
+// (or since created if the results don't have timestamps).
+func (st *stats) elapsed(created time.Time) time.Duration {
+	first, last := st.firstStart.Load(), st.lastEnd.Load()
+	if first == 0 || last == 0 {
+		return time.Since(created)
+	}
+	return time.Duration(last - first)
+}
+
+// summary returns the accumulated [Summary] for the given clock time.
+func (st *stats) summary(elapsed time.Duration) Summary {
+	s := Summary{
+		Requests: int(st.requests.Load()),
+		Errors:   int(st.errors.Load()),
+		Bytes:    st.bytes.Load(),
+		Fastest:  time.Duration(st.fastest.Load()),
+		Slowest:  time.Duration(st.slowest.Load()),
+		MaxLag:   time.Duration(st.maxLag.Load()),
+
+		Histogram: st.durations.snapshot(),
+		Statuses:  st.statuses.snapshot(),
+	}
+
+	s.Duration = elapsed
+	if s.Duration > 0 {
+		s.RPS = float64(s.Requests) / s.Duration.Seconds() // throughput
+	}
 
 	if s.Requests > 0 {
-		s.Average = requestDurationSum / time.Duration(s.Requests) // latency
+		s.Average = time.Duration(st.requestDurationSum.Load()) / time.Duration(s.Requests) // latency
+		s.AverageLag = time.Duration(st.lagSum.Load()) / time.Duration(s.Requests)
 		s.Success = (float64(s.Requests-s.Errors) / float64(s.Requests)) * 100
 	}
 
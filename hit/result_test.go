@@ -2,7 +2,9 @@ package hit
 
 import (
 	"fmt"
+	"maps"
 	"slices"
+	"sync"
 	"testing"
 	"time"
 )
@@ -80,3 +82,90 @@ func TestSummarizeNilResults(t *testing.T) {
 
 	_ = Summarize(nil)
 }
+
+// test that the warm-up results are excluded from the summary
+func TestSummarizeWarmup(t *testing.T) {
+
+	results := []Result{
+		{Duration: 900 * time.Millisecond, Warmup: true}, // e.g. a cold connection
+		{Duration: 100 * time.Millisecond},
+		{Duration: 300 * time.Millisecond},
+	}
+
+	s := Summarize(Results(slices.Values(results)))
+
+	if s.Requests != 2 {
+		t.Errorf("Requests: got = %d, want = %d\n", s.Requests, 2)
+	}
+
+	if s.Slowest != 300*time.Millisecond {
+		t.Errorf("Slowest: got = %v, want = %v\n", s.Slowest, 300*time.Millisecond)
+	}
+
+	if s.Warmup == nil || s.Warmup.Requests != 1 || s.Warmup.Slowest != 900*time.Millisecond {
+		t.Errorf("Warmup: got = %+v, want a summary of the 900ms request\n", s.Warmup)
+	}
+}
+
+// test that a summarizer can be read while results are still being added
+func TestSummarizerConcurrent(t *testing.T) {
+
+	sz := NewSummarizer()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 100 {
+				sz.Add(Result{Duration: time.Millisecond})
+				_ = sz.Summary() // e.g. a live progress report
+			}
+		})
+	}
+	wg.Wait()
+
+	if s := sz.Summary(); s.Requests != 400 {
+		t.Errorf("Requests: got = %d, want = %d\n", s.Requests, 400)
+	}
+}
+
+// test that the clock time is measured from the first start to the last end of the results
+func TestSummarizeDuration(t *testing.T) {
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	results := []Result{
+		{Start: start.Add(time.Second), End: start.Add(1500 * time.Millisecond), Duration: 500 * time.Millisecond},
+		{Start: start, End: start.Add(time.Second), Duration: time.Second}, // results may arrive out of order
+		{Start: start.Add(1500 * time.Millisecond), End: start.Add(2 * time.Second), Duration: 500 * time.Millisecond},
+	}
+
+	s := Summarize(Results(slices.Values(results)))
+
+	if s.Duration != 2*time.Second {
+		t.Errorf("Duration: got = %v, want = %v\n", s.Duration, 2*time.Second)
+	}
+	if s.RPS != 1.5 {
+		t.Errorf("RPS: got = %v, want = %v\n", s.RPS, 1.5)
+	}
+
+	// a single instantaneous result (e.g. with a coarse clock) has no throughput
+	instant := []Result{{Start: start, End: start}}
+	if s := Summarize(Results(slices.Values(instant))); s.Duration != 0 || s.RPS != 0 {
+		t.Errorf("Duration, RPS: got = %v, %v, want = 0s, 0\n", s.Duration, s.RPS)
+	}
+}
+
+// test that the responses are counted per status code
+func TestSummarizeStatuses(t *testing.T) {
+
+	results := []Result{
+		{Status: 200}, {Status: 200}, {Status: 503},
+		{Error: fmt.Errorf("connection refused")}, // no response
+	}
+
+	s := Summarize(Results(slices.Values(results)))
+
+	want := map[int]int{200: 2, 503: 1, 0: 1}
+	if !maps.Equal(s.Statuses, want) {
+		t.Errorf("Statuses: got = %v, want = %v\n", s.Statuses, want)
+	}
+}
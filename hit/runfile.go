@@ -0,0 +1,308 @@
+// This file defines the run files: the results of a run streamed to a file (one JSON line per result)
+// so that the run can be reported again (or compared with another run) once it's over, e.g.
+//
+//	{"format":"hit-run/1","version":"v1.2.0","go_version":"go1.25.1","target":"http://localhost:8082","start":"...","options":{...}}
+//	{"seq":0,"worker":0,"start":1735732800000000000,"end":1735732800012000000,"dur":12000000,"status":200,"bytes":512,"method":"GET","url":"http://localhost:8082"}
+//	...
+
+package hit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runFormat is the format (and its version) of the run files
+const runFormat = "hit-run/1"
+
+// RunInfo is the metadata of a run (the first line of a run file).
+type RunInfo struct {
+	Version   string     `json:"version"`    // Version is the version of hit
+	GoVersion string     `json:"go_version"` // GoVersion is the version of go hit was built with
+	Target    string     `json:"target"`     // Target is what the requests were sent to (e.g. a url or a scenario file)
+	Start     time.Time  `json:"start"`      // Start is the time the run started
+	Options   RunOptions `json:"options"`    // Options are the options of the run
+}
+
+// RunOptions are the options of a run recorded in a run file (see [Options]).
+type RunOptions struct {
+	Requests       int           `json:"requests"` // number of requests planned (0 if unknown, e.g. a replay)
+	Concurrency    int           `json:"concurrency"`
+	RPS            int           `json:"rps,omitempty"`
+	Warmup         int           `json:"warmup,omitempty"`
+	WarmupDuration time.Duration `json:"warmup_duration,omitempty"`
+	Buffer         int           `json:"buffer,omitempty"`
+	Ordered        bool          `json:"ordered,omitempty"`
+	Trace          bool          `json:"trace,omitempty"`
+	Auth           bool          `json:"auth,omitempty"` // the requests were authenticated (the credentials are not recorded)
+}
+
+// NewRunInfo returns the [RunInfo] of a run of n requests (0 if unknown) sent to target with opts
+// that starts now.
+func NewRunInfo(target string, n int, opts Options) RunInfo {
+	opts = withDefaults(opts)
+	return RunInfo{
+		Version:   Version(),
+		GoVersion: runtime.Version(),
+		Target:    target,
+		Start:     time.Now(),
+		Options: RunOptions{
+			Requests:       n,
+			Concurrency:    opts.Concurrency,
+			RPS:            opts.RPS,
+			Warmup:         opts.Warmup,
+			WarmupDuration: opts.WarmupDuration,
+			Buffer:         opts.Buffer,
+			Ordered:        opts.Ordered,
+			Trace:          opts.Trace,
+			Auth:           opts.Auth != nil,
+		},
+	}
+}
+
+// Version returns the version of hit (i.e. of its module) from the build information
+// ("(devel)" for a build of the module itself without version control information).
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown)"
+	}
+
+	const module = "github.com/faizan2786/gobyexample"
+	if info.Main.Path == module {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == module {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// runHeader is the first line of a run file
+type runHeader struct {
+	Format string `json:"format"`
+	RunInfo
+}
+
+// runRecord is a [Result] in a run file
+// (the times are in unix nanoseconds and the durations in nanoseconds to keep the lines short)
+type runRecord struct {
+	Seq       int    `json:"seq"`
+	Worker    int    `json:"worker"`
+	Target    string `json:"target,omitempty"`
+	Start     int64  `json:"start,omitempty"`
+	End       int64  `json:"end,omitempty"`
+	Duration  int64  `json:"dur"`
+	Lag       int64  `json:"lag,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Bytes     int64  `json:"bytes,omitempty"`
+	Method    string `json:"method,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorKind string `json:"error_kind,omitempty"` // "url" or "auth" (to restore the type of the error)
+	TraceID   string `json:"trace_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Warmup    bool   `json:"warmup,omitempty"`
+	Skipped   bool   `json:"skipped,omitempty"`
+	Abandoned bool   `json:"abandoned,omitempty"`
+}
+
+// RunWriter streams the results of a run to a run file (see [ReadRun]).
+// Its Add method can be used as the OnResult hook of the run (see [Hooks]).
+// It is safe for concurrent use.
+type RunWriter struct {
+	mu  sync.Mutex // guards the writer
+	w   *bufio.Writer
+	enc *json.Encoder
+	err error // the first write error (the next writes are skipped)
+}
+
+// NewRunWriter writes the header of a run file with the metadata of the run to w
+// and returns a [RunWriter] to write its results.
+// The results are buffered: call Flush once the run is over.
+func NewRunWriter(w io.Writer, info RunInfo) (*RunWriter, error) {
+	bw := bufio.NewWriter(w)
+	rw := &RunWriter{w: bw, enc: json.NewEncoder(bw)}
+
+	if err := rw.enc.Encode(runHeader{Format: runFormat, RunInfo: info}); err != nil {
+		return nil, fmt.Errorf("writing run file header: %w", err)
+	}
+	return rw, nil
+}
+
+// Add writes a result to the run file.
+// A write error is reported by Flush.
+func (rw *RunWriter) Add(r Result) {
+	rec := runRecord{
+		Seq:       r.Seq,
+		Worker:    r.Worker,
+		Target:    r.Target,
+		Duration:  int64(r.Duration),
+		Lag:       int64(r.Lag),
+		Status:    r.Status,
+		Bytes:     r.Bytes,
+		Method:    r.Method,
+		URL:       r.URL,
+		TraceID:   r.TraceID,
+		RequestID: r.RequestID,
+		Warmup:    r.Warmup,
+		Skipped:   r.Skipped,
+		Abandoned: r.Abandoned,
+	}
+	if !r.Start.IsZero() {
+		rec.Start = r.Start.UnixNano()
+	}
+	if !r.End.IsZero() {
+		rec.End = r.End.UnixNano()
+	}
+	if r.Error != nil {
+		rec.Error, rec.ErrorKind = encodeError(r.Error)
+	}
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.err != nil {
+		return
+	}
+	if err := rw.enc.Encode(rec); err != nil {
+		rw.err = fmt.Errorf("writing run file: %w", err)
+	}
+}
+
+// Flush writes the buffered results to the underlying writer
+// and returns the first error that occurred while writing the run file (if any).
+func (rw *RunWriter) Flush() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.err != nil {
+		return rw.err
+	}
+	if err := rw.w.Flush(); err != nil {
+		rw.err = fmt.Errorf("writing run file: %w", err)
+	}
+	return rw.err
+}
+
+// ReadRun reads the metadata of a run file from r
+// and returns an iterator over its results.
+// The iterator stops after yielding an error for a malformed line.
+func ReadRun(r io.Reader) (RunInfo, iter.Seq2[Result, error], error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var h runHeader
+	if err := dec.Decode(&h); err != nil {
+		return RunInfo{}, nil, fmt.Errorf("reading run file header: %w", err)
+	}
+	if h.Format != runFormat {
+		return RunInfo{}, nil, fmt.Errorf("not a run file: got format %q, want %q", h.Format, runFormat)
+	}
+
+	results := func(yield func(Result, error) bool) {
+		for line := 2; ; line++ {
+			var rec runRecord
+			if err := dec.Decode(&rec); err == io.EOF {
+				return
+			} else if err != nil {
+				yield(Result{}, fmt.Errorf("run file line %d: %w", line, err))
+				return
+			}
+
+			if !yield(rec.result(), nil) {
+				return
+			}
+		}
+	}
+
+	return h.RunInfo, results, nil
+}
+
+// LoadRun reads a whole run file from r (see [ReadRun]).
+func LoadRun(r io.Reader) (RunInfo, []Result, error) {
+	info, results, err := ReadRun(r)
+	if err != nil {
+		return RunInfo{}, nil, err
+	}
+
+	var list []Result
+	for r, err := range results {
+		if err != nil {
+			return RunInfo{}, nil, err
+		}
+		list = append(list, r)
+	}
+	return info, list, nil
+}
+
+func (rec runRecord) result() Result {
+	r := Result{
+		Seq:       rec.Seq,
+		Worker:    rec.Worker,
+		Target:    rec.Target,
+		Duration:  time.Duration(rec.Duration),
+		Lag:       time.Duration(rec.Lag),
+		Status:    rec.Status,
+		Bytes:     rec.Bytes,
+		Method:    rec.Method,
+		URL:       rec.URL,
+		TraceID:   rec.TraceID,
+		RequestID: rec.RequestID,
+		Warmup:    rec.Warmup,
+		Skipped:   rec.Skipped,
+		Abandoned: rec.Abandoned,
+	}
+	if rec.Start != 0 {
+		r.Start = time.Unix(0, rec.Start)
+	}
+	if rec.End != 0 {
+		r.End = time.Unix(0, rec.End)
+	}
+	if rec.Error != "" {
+		r.Error = decodeError(rec.Error, rec.ErrorKind, rec.Method, rec.URL)
+	}
+	return r
+}
+
+// encodeError returns the message of an error and its kind
+// (the kind of error the summary tells apart, see [Summarizer])
+func encodeError(err error) (msg, kind string) {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return authErr.Err.Error(), "auth"
+	}
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err.Error(), "url" // (the url is recorded with the request)
+	}
+	return err.Error(), ""
+}
+
+// decodeError restores an error encoded by encodeError.
+func decodeError(msg, kind, method, u string) error {
+	err := errors.New(msg)
+	switch kind {
+	case "auth":
+		return &AuthError{Err: err}
+	case "url":
+		// (like the errors of an http.Client, e.g. `Get "http://...": connection refused`)
+		op := method
+		if len(method) > 1 {
+			op = method[:1] + strings.ToLower(method[1:])
+		}
+		return &url.Error{Op: op, URL: u, Err: err}
+	}
+	return err
+}
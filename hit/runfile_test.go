@@ -0,0 +1,95 @@
+package hit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test that the results written to a run file are read back (with the metadata of the run)
+func TestRunFile(t *testing.T) {
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	results := []Result{
+		{Seq: 0, Worker: 1, Target: "items", Start: start, End: start.Add(12 * time.Millisecond), Duration: 12 * time.Millisecond,
+			Status: 200, Bytes: 512, Method: "GET", URL: "http://localhost/items", RequestID: "id-0", TraceID: "trace-0"},
+		{Seq: 1, Start: start, End: start.Add(time.Millisecond), Duration: time.Millisecond, Method: "POST", URL: "http://localhost/items",
+			Error: &url.Error{Op: "Post", URL: "http://localhost/items", Err: errors.New("connection refused")}},
+		{Seq: 2, Error: &AuthError{Err: errors.New("token expired")}},
+		{Seq: 3, Warmup: true, Duration: time.Second},
+		{Skipped: true, Error: context.Canceled},
+	}
+
+	opts := Options{Concurrency: 2, RPS: 10}
+	info := NewRunInfo("http://localhost/items", 5, opts)
+
+	var file bytes.Buffer
+	rw, err := NewRunWriter(&file, info)
+	if err != nil {
+		t.Fatalf("NewRunWriter() = %v; want no error\n", err)
+	}
+	for _, r := range results {
+		rw.Add(r)
+	}
+	if err := rw.Flush(); err != nil {
+		t.Fatalf("Flush() = %v; want no error\n", err)
+	}
+
+	// a line per result (and a header)
+	if got := strings.Count(file.String(), "\n"); got != len(results)+1 {
+		t.Errorf("lines: got = %d, want = %d\n", got, len(results)+1)
+	}
+
+	gotInfo, got, err := LoadRun(&file)
+	if err != nil {
+		t.Fatalf("LoadRun() = %v; want no error\n", err)
+	}
+
+	if gotInfo.Target != info.Target || !gotInfo.Start.Equal(info.Start) || gotInfo.Options != info.Options || gotInfo.GoVersion == "" {
+		t.Errorf("RunInfo: got = %+v, want = %+v\n", gotInfo, info)
+	}
+	if gotInfo.Options.Concurrency != 2 || gotInfo.Options.RPS != 10 || gotInfo.Options.Requests != 5 {
+		t.Errorf("Options: got = %+v, want 5 requests, concurrency 2 and 10 rps\n", gotInfo.Options)
+	}
+
+	if len(got) != len(results) {
+		t.Fatalf("LoadRun() returned %d results; want %d\n", len(got), len(results))
+	}
+	for i, want := range results {
+		g := got[i]
+		if g.Seq != want.Seq || g.Worker != want.Worker || g.Target != want.Target || g.Duration != want.Duration ||
+			g.Status != want.Status || g.Bytes != want.Bytes || g.Method != want.Method || g.URL != want.URL ||
+			g.RequestID != want.RequestID || g.TraceID != want.TraceID || g.Warmup != want.Warmup || g.Skipped != want.Skipped ||
+			!g.Start.Equal(want.Start) || !g.End.Equal(want.End) {
+			t.Errorf("result %d: got = %+v, want = %+v\n", i, g, want)
+		}
+		if (g.Error == nil) != (want.Error == nil) || (g.Error != nil && g.Error.Error() != want.Error.Error()) {
+			t.Errorf("result %d error: got = %v, want = %v\n", i, g.Error, want.Error)
+		}
+	}
+
+	// the type of the errors is restored
+	var rerr *url.Error
+	if !errors.As(got[1].Error, &rerr) {
+		t.Errorf("result 1 error: got = %T, want a *url.Error\n", got[1].Error)
+	}
+
+	// the summary of the run file is the summary of the run
+	want := Summarize(Results(slices.Values(results)))
+	s := Summarize(Results(slices.Values(got)))
+	if s.Requests != want.Requests || s.Errors != want.Errors || s.AuthErrors != 1 || s.Warmup == nil || !s.Partial || s.Duration != want.Duration {
+		t.Errorf("Summarize(): got = %+v, want = %+v\n", s, want)
+	}
+}
+
+func TestReadRunNotARunFile(t *testing.T) {
+
+	if _, _, err := ReadRun(strings.NewReader(`{"time": "2025-01-01T12:00:00Z", "method": "GET", "path": "/"}`)); err == nil {
+		t.Errorf("ReadRun() = nil; want an error\n")
+	}
+}
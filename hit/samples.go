@@ -0,0 +1,149 @@
+// This file defines the samples of a [Summary]: the slowest results and the distinct errors of a run
+// (so that the summary shows which requests were slow and what the errors said, not just counts)
+
+package hit
+
+import (
+	"cmp"
+	"container/heap"
+	"errors"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sampleSize is the number of slowest results (and distinct errors) kept in a [Summary]
+const sampleSize = 10
+
+// ErrorSample is a distinct error message of a run (see [Summary]).
+type ErrorSample struct {
+	Message string    // Message is the error message (without the url of the request)
+	Count   int       // Count is the number of results that failed with this message
+	First   time.Time // First is the time the first result failed with this message
+	Last    time.Time // Last is the time the last result failed with this message
+	Result  Result    // Result is the first result that failed with this message (e.g. its request details)
+}
+
+// slowest keeps the k slowest results added to it.
+// A result faster than all the kept ones is skipped without locking (i.e. the common case of a long run).
+type slowest struct {
+	k       int
+	fastest atomic.Int64 // duration of the fastest kept result (once k results are kept)
+
+	mu      sync.Mutex
+	results resultHeap
+}
+
+func (s *slowest) add(r Result) {
+	if int64(r.Duration) <= s.fastest.Load() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.results) < s.k {
+		heap.Push(&s.results, r)
+	} else if r.Duration > s.results[0].Duration {
+		s.results[0] = r
+		heap.Fix(&s.results, 0)
+	}
+
+	if len(s.results) == s.k {
+		s.fastest.Store(int64(s.results[0].Duration))
+	}
+}
+
+// list returns the kept results (slowest first).
+func (s *slowest) list() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := slices.Clone(s.results)
+	slices.SortFunc(list, func(a, b Result) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	return list
+}
+
+// resultHeap is a min-heap of results by duration (implements [heap.Interface]).
+type resultHeap []Result
+
+func (h resultHeap) Len() int           { return len(h) }
+func (h resultHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h resultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x any)        { *h = append(*h, x.(Result)) }
+func (h *resultHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// errorSamples keeps the first k distinct error messages added to it (with their counts).
+// The errors of the other messages are only counted by the summary (i.e. the memory stays bounded
+// even if every error is different).
+//
+// Unlike the rest of the summarizer, it takes a lock:
+// the failed results are expected to be the exception (and are slow anyway, e.g. timeouts).
+type errorSamples struct {
+	k int
+
+	mu      sync.Mutex
+	samples []ErrorSample  // in the order the messages were first seen
+	index   map[string]int // index of each message in samples
+}
+
+func (e *errorSamples) add(r Result) {
+	msg := errorMessage(r.Error)
+
+	at := r.End
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if i, ok := e.index[msg]; ok {
+		s := &e.samples[i]
+		s.Count++
+		// the results may be added out of order (e.g. by concurrent workers)
+		if at.Before(s.First) {
+			s.First = at
+		}
+		if at.After(s.Last) {
+			s.Last = at
+		}
+		return
+	}
+
+	if len(e.samples) == e.k {
+		return
+	}
+	if e.index == nil {
+		e.index = make(map[string]int, e.k)
+	}
+	e.index[msg] = len(e.samples)
+	e.samples = append(e.samples, ErrorSample{Message: msg, Count: 1, First: at, Last: at, Result: r})
+}
+
+// list returns the kept error samples (in the order they were first seen).
+func (e *errorSamples) list() []ErrorSample {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return slices.Clone(e.samples)
+}
+
+// errorMessage returns the message of err without the url of the request
+// (otherwise, the same error on different urls, e.g. of a template, would be a distinct message).
+func errorMessage(err error) string {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,75 @@
+package hit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+// test that the summary keeps the slowest results (slowest first)
+func TestSummarizeSlowestResults(t *testing.T) {
+
+	var results []Result
+	for i := range 3 * sampleSize {
+		results = append(results, Result{Seq: i, Duration: time.Duration(i) * time.Millisecond})
+	}
+
+	s := Summarize(Results(slices.Values(results)))
+
+	if len(s.SlowestResults) != sampleSize {
+		t.Fatalf("SlowestResults: got %d results, want %d\n", len(s.SlowestResults), sampleSize)
+	}
+	for i, r := range s.SlowestResults {
+		if want := 3*sampleSize - 1 - i; r.Seq != want {
+			t.Errorf("SlowestResults[%d].Seq: got = %d, want = %d\n", i, r.Seq, want)
+		}
+	}
+}
+
+// test that the summary keeps the first distinct errors with their counts and timestamps
+func TestSummarizeErrorSamples(t *testing.T) {
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	refused := errors.New("connection refused")
+
+	results := []Result{
+		{Seq: 0, End: start.Add(time.Second), Error: &url.Error{Op: "Get", URL: "http://localhost/items/1", Err: refused}},
+		{Seq: 1, End: start.Add(2 * time.Second)}, // not an error
+		{Seq: 2, End: start.Add(3 * time.Second), Error: errors.New("timeout")},
+		// the same error on another url (results may arrive out of order)
+		{Seq: 3, End: start, Error: &url.Error{Op: "Get", URL: "http://localhost/items/2", Err: refused}},
+	}
+	// more distinct errors than kept
+	for i := range sampleSize {
+		results = append(results, Result{Seq: 4 + i, End: start, Error: fmt.Errorf("error %d", i)})
+	}
+
+	s := Summarize(Results(slices.Values(results)))
+
+	if len(s.ErrorSamples) != sampleSize {
+		t.Fatalf("ErrorSamples: got %d samples, want %d\n", len(s.ErrorSamples), sampleSize)
+	}
+
+	got := s.ErrorSamples[0]
+	if got.Message != "connection refused" || got.Count != 2 {
+		t.Errorf("ErrorSamples[0]: got = %q (%d), want = %q (%d)\n", got.Message, got.Count, "connection refused", 2)
+	}
+	if !got.First.Equal(start) || !got.Last.Equal(start.Add(time.Second)) {
+		t.Errorf("ErrorSamples[0] timestamps: got = %v to %v, want = %v to %v\n", got.First, got.Last, start, start.Add(time.Second))
+	}
+	if got.Result.Seq != 0 {
+		t.Errorf("ErrorSamples[0].Result.Seq: got = %d, want = %d (the first result)\n", got.Result.Seq, 0)
+	}
+
+	if got := s.ErrorSamples[1]; got.Message != "timeout" || got.Count != 1 {
+		t.Errorf("ErrorSamples[1]: got = %q (%d), want = %q (%d)\n", got.Message, got.Count, "timeout", 1)
+	}
+
+	// the errors of the messages that aren't kept are still counted
+	if want := 3 + sampleSize; s.Errors != want {
+		t.Errorf("Errors: got = %d, want = %d\n", s.Errors, want)
+	}
+}
@@ -0,0 +1,97 @@
+// This file defines a scenario: a set of named and weighted requests that can be
+// saved to (and loaded from) a JSON file instead of building the requests by hand
+
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Scenario is a set of weighted requests sent as a traffic mix (see [SendTargets]).
+type Scenario struct {
+	Requests []ScenarioRequest `json:"requests"`
+}
+
+// ScenarioRequest is a single request of a [Scenario].
+// Its URL, header values and body are templates (see [RequestTemplate]).
+type ScenarioRequest struct {
+	Name   string      `json:"name"`
+	Weight int         `json:"weight,omitempty"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// LoadScenario reads a [Scenario] from the JSON file at path.
+func LoadScenario(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadScenario(f)
+}
+
+// ReadScenario reads a [Scenario] in JSON from r.
+func ReadScenario(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decoding scenario: %w", err)
+	}
+	if len(s.Requests) == 0 {
+		return nil, fmt.Errorf("scenario has no requests")
+	}
+	return &s, nil
+}
+
+// Write writes the scenario in (indented) JSON to w.
+func (s *Scenario) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // keep the urls and bodies readable
+	return enc.Encode(s)
+}
+
+// Targets returns a templated [Target] for each request of the scenario.
+func (s *Scenario) Targets() ([]Target, error) {
+	targets := make([]Target, 0, len(s.Requests))
+	for _, r := range s.Requests {
+		method := r.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		tmpl, err := NewRequestTemplate(method, r.URL, r.Header, r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("scenario request %q: %w", r.Name, err)
+		}
+		targets = append(targets, Target{Name: r.Name, Weight: r.Weight, Template: tmpl})
+	}
+	return targets, nil
+}
+
+// add appends the request to the scenario with a unique name
+// (by appending a number to a name that is already taken, e.g. "GET /items #2").
+func (s *Scenario) add(r ScenarioRequest) {
+	name := r.Name
+	for i := 2; s.hasName(name); i++ {
+		name = fmt.Sprintf("%s #%d", r.Name, i)
+	}
+	r.Name = name
+	s.Requests = append(s.Requests, r)
+}
+
+func (s *Scenario) hasName(name string) bool {
+	for _, r := range s.Requests {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// This file defines named targets that let a single run send a weighted mix of requests
+// (e.g. 70% reads, 20% searches and 10% writes) through the same pipeline
+
+package hit
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+)
+
+// Target is a named [http.Request] that takes a weighted share of the requests in a run.
+// Either Request or Template must be set.
+type Target struct {
+	Name     string           // Name tags each [Result] sent for this target
+	Weight   int              // Weight is the relative share of requests (Default: 1)
+	Request  *http.Request    // Request is cloned for each request sent to the target
+	Template *RequestTemplate // Template renders a new request for each request sent to the target
+
+	url string // the url of Request (formatted once for all the results, see urlOf)
+}
+
+// mix picks a [Target] for each request according to the target weights.
+type mix struct {
+	targets []Target
+	cum     []int // cumulative weights (i.e. cum[i] is the sum of weights of targets[0..i])
+}
+
+func newMix(targets []Target) (*mix, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+
+	m := &mix{
+		targets: make([]Target, len(targets)),
+		cum:     make([]int, len(targets)),
+	}
+
+	names := make(map[string]bool, len(targets))
+	total := 0
+	for i, t := range targets {
+		if (t.Request == nil) == (t.Template == nil) {
+			return nil, fmt.Errorf("target %q: exactly one of request or template must be set", t.Name)
+		}
+		if t.Weight < 0 {
+			return nil, fmt.Errorf("target %q: weight must not be negative: got %d", t.Name, t.Weight)
+		}
+		if t.Weight == 0 {
+			t.Weight = 1
+		}
+		// the per target summary is keyed by name, so names must be unique
+		if names[t.Name] {
+			return nil, fmt.Errorf("target %q: duplicate target name", t.Name)
+		}
+		names[t.Name] = true
+
+		if t.Request != nil {
+			t.url = t.Request.URL.String()
+		}
+
+		total += t.Weight
+		m.targets[i] = t
+		m.cum[i] = total
+	}
+
+	return m, nil
+}
+
+// pick returns a random target where each target's chance is proportional to its weight.
+func (m *mix) pick() *Target {
+	if len(m.targets) == 1 {
+		return &m.targets[0]
+	}
+
+	// pick a random point in [0, total) and find the first target whose cumulative weight is above it
+	n := rand.IntN(m.cum[len(m.cum)-1])
+	i := sort.SearchInts(m.cum, n+1)
+	return &m.targets[i]
+}
+
+// request returns a new request to send to the target.
+func (t *Target) request(ctx context.Context, data TemplateData) (*http.Request, error) {
+	if t.Template != nil {
+		return t.Template.Request(ctx, data)
+	}
+	return cloneRequest(ctx, t.Request), nil
+}
+
+// urlOf returns the url of a request sent to the target
+// (the url of its request formatted once, unless the request's url was changed, e.g. by a middleware).
+func (t *Target) urlOf(req *http.Request) string {
+	if t.url != "" && *req.URL == *t.Request.URL {
+		return t.url
+	}
+	return req.URL.String()
+}
+
+// describe returns the method and the url of the requests to the target (the url template of a templated target)
+// e.g. to trace the error of a request that couldn't be built.
+func (t *Target) describe() (method, url string) {
+	switch {
+	case t.Template != nil:
+		return t.Template.method, t.Template.rawURL
+	case t.Request != nil:
+		return t.Request.Method, t.Request.URL.String()
+	}
+	return "", ""
+}
+
+// cloneRequest clones req with ctx.
+// Unlike [http.Request.Clone], it also gives the clone its own copy of the body
+// (if possible) so that requests with a body (e.g. POST) can be sent more than once.
+func cloneRequest(ctx context.Context, req *http.Request) *http.Request {
+	clone := req.Clone(ctx)
+
+	// GetBody is set by http.NewRequest for the common body types (bytes, strings, etc.)
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			clone.Body = body
+		}
+	}
+	return clone
+}
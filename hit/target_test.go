@@ -0,0 +1,163 @@
+package hit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewMixInvalid(t *testing.T) {
+
+	req := getTestHttpRequest()
+
+	testCases := []struct {
+		name    string
+		targets []Target
+	}{
+		{name: "no_targets", targets: nil},
+		{name: "nil_request", targets: []Target{{Name: "a"}}},
+		{name: "negative_weight", targets: []Target{{Name: "a", Weight: -1, Request: req}}},
+		{name: "duplicate_name", targets: []Target{{Name: "a", Request: req}, {Name: "a", Request: req}}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newMix(tt.targets); err == nil {
+				t.Errorf("newMix() error = <nil>; want an error")
+			}
+		})
+	}
+}
+
+// test that the targets are picked according to their weights
+func TestMixPick(t *testing.T) {
+
+	req := getTestHttpRequest()
+
+	m, err := newMix([]Target{
+		{Name: "reads", Weight: 70, Request: req},
+		{Name: "searches", Weight: 20, Request: req},
+		{Name: "writes", Weight: 10, Request: req},
+		{Name: "never", Weight: 0, Request: req}, // zero weight defaults to 1
+	})
+	if err != nil {
+		t.Fatalf("newMix() = %v; want no error\n", err)
+	}
+
+	const N = 100_000
+	counts := make(map[string]int)
+	for range N {
+		counts[m.pick().Name] += 1
+	}
+
+	// allow a tolerance of 1% of the requests for each target
+	want := map[string]int{"reads": 69_307, "searches": 19_802, "writes": 9_901, "never": 990}
+	for name, w := range want {
+		if got := counts[name]; got < w-N/100 || got > w+N/100 {
+			t.Errorf("target %q picked %d times; want about %d\n", name, got, w)
+		}
+	}
+}
+
+// test that SendTargets tags each result with its target and summarizes per target
+func TestSendTargets(t *testing.T) {
+	const N int = 100
+
+	opts := Options{Concurrency: 4}
+
+	// echo the request body back as the number of bytes received
+	opts.Send = func(req *http.Request) Result {
+		body, _ := io.ReadAll(req.Body)
+		return Result{Status: http.StatusOK, Bytes: int64(len(body))}
+	}
+
+	reads, _ := http.NewRequest(http.MethodGet, "/items", http.NoBody)
+	writes, _ := http.NewRequest(http.MethodPost, "/items", strings.NewReader("item"))
+
+	results, err := SendTargets(context.Background(), N, opts,
+		Target{Name: "reads", Weight: 1, Request: reads},
+		Target{Name: "writes", Weight: 1, Request: writes},
+	)
+	if err != nil {
+		t.Fatalf("SendTargets() = %v; want no error\n", err)
+	}
+
+	s := Summarize(results)
+
+	if s.Requests != N {
+		t.Fatalf("Requests: got = %d, want = %d\n", s.Requests, N)
+	}
+
+	if len(s.Targets) != 2 {
+		t.Fatalf("len(Targets): got = %d, want = %d\n", len(s.Targets), 2)
+	}
+
+	r, w := s.Targets["reads"], s.Targets["writes"]
+	if r.Requests+w.Requests != N {
+		t.Errorf("reads + writes: got = %d, want = %d\n", r.Requests+w.Requests, N)
+	}
+
+	// each write must have sent its own copy of the body
+	if w.Bytes != int64(w.Requests*len("item")) {
+		t.Errorf("writes Bytes: got = %d, want = %d\n", w.Bytes, w.Requests*len("item"))
+	}
+	if r.Bytes != 0 {
+		t.Errorf("reads Bytes: got = %d, want = %d\n", r.Bytes, 0)
+	}
+}
+
+// test that the result of a request that couldn't be built still tells which request it was
+func TestSendTargetsInvalidRequest(t *testing.T) {
+
+	tmpl, err := NewRequestTemplate(http.MethodPut, "http://localhost/items/{{randInt 10 1}}", nil, "")
+	if err != nil {
+		t.Fatalf("NewRequestTemplate() = %v; want no error\n", err)
+	}
+
+	opts := Options{}
+	opts.Send = func(_ *http.Request) Result {
+		return Result{Status: http.StatusOK}
+	}
+
+	results, err := SendTargets(context.Background(), 1, opts, Target{Template: tmpl})
+	if err != nil {
+		t.Fatalf("SendTargets() = %v; want no error\n", err)
+	}
+
+	for r := range results {
+		if r.Error == nil {
+			t.Errorf("Error: got = <nil>, want a render error\n")
+		}
+		if r.Method != http.MethodPut || r.URL != "http://localhost/items/{{randInt 10 1}}" {
+			t.Errorf("Method, URL: got = %q, %q, want = %q, %q\n", r.Method, r.URL, http.MethodPut, "http://localhost/items/{{randInt 10 1}}")
+		}
+	}
+}
+
+// test that the url of a result is the url sent (even if a middleware changed it)
+// and not the url of the target's request formatted once by the mix
+func TestSendTargetsURL(t *testing.T) {
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/items", http.NoBody)
+
+	opts := Options{Concurrency: 1, Send: noopSend}
+	opts.Middleware = []Middleware{func(next SendFunc) SendFunc {
+		return func(r *http.Request) Result {
+			r.URL.Path = "/other"
+			return next(r)
+		}
+	}}
+
+	results, err := SendTargets(context.Background(), 1, opts, Target{Request: req})
+	if err != nil {
+		t.Fatalf("SendTargets() = %v; want no error\n", err)
+	}
+
+	for r := range results {
+		if want := "http://localhost/other"; r.URL != want {
+			t.Errorf("URL: got = %s, want = %s\n", r.URL, want)
+		}
+	}
+}
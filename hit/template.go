@@ -0,0 +1,217 @@
+// This file defines request templates that render a unique URL, headers and body for each request
+// (so that caches and idempotency keys on the server don't skew the results)
+//
+// Templates use the text/template syntax with the following values and generators:
+//
+//	{{.Seq}}                 sequence number of the request (0, 1, 2, ...)
+//	{{.Worker}}              ID of the dispatch worker sending the request (0 to Concurrency-1)
+//	{{uuid}}                 a random (version 4) UUID
+//	{{randInt 1 100}}        a random int in the range [1, 100]
+//	{{randString 8}}         a random alphanumeric string of length 8
+//	{{timestamp}}            current unix time in milliseconds
+//	{{timestamp "15:04:05"}} current time in the given layout
+//	{{pick "a" "b" "c"}}     a random pick from the list
+//	{{.Data.field}}          value of the field of the record from the data [Feeder]
+//
+// A literal "{{" is written {{"{{"}} (see [EscapeTemplate] to send a text as is).
+
+package hit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data a [RequestTemplate] is rendered with.
+type TemplateData struct {
+	Seq    int    // Seq is the sequence number of the request in the run
+	Worker int    // Worker is the ID of the worker sending the request
+	Data   Record // Data is the record of the data [Feeder] (nil if there is no feeder)
+}
+
+// RequestTemplate renders a new [http.Request] for each request sent.
+type RequestTemplate struct {
+	method string
+	rawURL string // the url template (e.g. to trace a request that fails to render)
+	url    *template.Template
+	header map[string][]*template.Template
+	body   *template.Template // nil if the request has no body
+}
+
+// templateFuncs are the generators available in the templates.
+var templateFuncs = template.FuncMap{
+	"uuid":       newUUID,
+	"randInt":    randInt,
+	"randString": randString,
+	"timestamp":  timestamp,
+	"pick":       pick,
+}
+
+// NewRequestTemplate parses the url, header values and body as templates.
+// It returns an error if the method isn't valid (see [ValidMethod]) or any of them isn't a valid template.
+func NewRequestTemplate(method, url string, header http.Header, body string) (*RequestTemplate, error) {
+	if !ValidMethod(method) {
+		return nil, fmt.Errorf("invalid method %q", method)
+	}
+
+	t := &RequestTemplate{
+		method: method,
+		rawURL: url,
+		header: make(map[string][]*template.Template, len(header)),
+	}
+
+	var err error
+	if t.url, err = parseTemplate("url", url); err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		for _, v := range values {
+			vt, err := parseTemplate("header "+key, v)
+			if err != nil {
+				return nil, err
+			}
+			t.header[key] = append(t.header[key], vt)
+		}
+	}
+
+	if body != "" {
+		if t.body, err = parseTemplate("body", body); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// EscapeTemplate escapes a text so that its template renders it as is
+// (e.g. a JSON body with a literal "{{", or an imported request sent exactly as it was captured).
+func EscapeTemplate(text string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+// ValidMethod reports whether m is a valid http method (a token, e.g. GET or PURGE).
+func ValidMethod(m string) bool {
+	return m != "" && !strings.ContainsFunc(m, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r))
+	})
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return t, nil
+}
+
+// Request renders a new [http.Request] with the given data and context.
+func (t *RequestTemplate) Request(ctx context.Context, data TemplateData) (*http.Request, error) {
+
+	// a single buffer is reused to render all the parts of the request
+	var buf bytes.Buffer
+
+	render := func(tmpl *template.Template) (string, error) {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("rendering %s template: %w", tmpl.Name(), err)
+		}
+		return buf.String(), nil
+	}
+
+	url, err := render(t.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader = http.NoBody
+	if t.body != nil {
+		text, err := render(t.body)
+		if err != nil {
+			return nil, err
+		}
+		// (NewRequest sets the ContentLength and GetBody for a strings.Reader)
+		body = strings.NewReader(text)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range t.header {
+		for _, vt := range values {
+			v, err := render(vt)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Add(key, v)
+		}
+	}
+
+	return req, nil
+}
+
+// template generators
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var u [16]byte
+	rand.Read(u[:]) // never returns an error
+
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// randInt returns a random int in the range [min, max].
+func randInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("randInt: max(=%d) is less than min(=%d)", max, min)
+	}
+	return min + mathrand.IntN(max-min+1), nil
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// randString returns a random alphanumeric string of length n.
+func randString(n int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("randString: negative length %d", n)
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphanumeric[mathrand.IntN(len(alphanumeric))]
+	}
+	return string(b), nil
+}
+
+// timestamp returns the current time in the given layout
+// or the current unix time in milliseconds if no layout is given.
+func timestamp(layout ...string) string {
+	now := time.Now()
+	if len(layout) == 0 {
+		return fmt.Sprint(now.UnixMilli())
+	}
+	return now.Format(layout[0])
+}
+
+// pick returns a random item from the list.
+func pick(items ...any) (any, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("pick: empty list")
+	}
+	return items[mathrand.IntN(len(items))], nil
+}
@@ -0,0 +1,124 @@
+package hit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestRequestTemplate(t *testing.T) {
+
+	header := http.Header{"X-Request-Id": {"{{uuid}}"}, "X-Worker": {"{{.Worker}}"}}
+
+	tmpl, err := NewRequestTemplate(
+		http.MethodPost,
+		"http://localhost/items/{{.Seq}}?q={{pick \"a\" \"b\"}}",
+		header,
+		`{"id": {{randInt 5 5}}, "name": "{{randString 6}}"}`,
+	)
+	if err != nil {
+		t.Fatalf("NewRequestTemplate() = %v; want no error\n", err)
+	}
+
+	req, err := tmpl.Request(context.Background(), TemplateData{Seq: 42, Worker: 3})
+	if err != nil {
+		t.Fatalf("Request() = %v; want no error\n", err)
+	}
+
+	if req.Method != http.MethodPost {
+		t.Errorf("Method: got = %s, want = %s\n", req.Method, http.MethodPost)
+	}
+
+	if got := req.URL.String(); !regexp.MustCompile(`^http://localhost/items/42\?q=[ab]$`).MatchString(got) {
+		t.Errorf("URL: got = %s, want = http://localhost/items/42?q=<a|b>\n", got)
+	}
+
+	if got := req.Header.Get("X-Worker"); got != "3" {
+		t.Errorf("X-Worker header: got = %s, want = %s\n", got, "3")
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if got := req.Header.Get("X-Request-Id"); !uuid.MatchString(got) {
+		t.Errorf("X-Request-Id header: got = %s, want a version 4 uuid\n", got)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if got := string(body); !regexp.MustCompile(`^{"id": 5, "name": "[a-zA-Z0-9]{6}"}$`).MatchString(got) {
+		t.Errorf("Body: got = %s, want = {\"id\": 5, \"name\": \"<6 random chars>\"}\n", got)
+	}
+
+	// the body can be read again (e.g. when a request is redirected or retried)
+	if req.GetBody == nil {
+		t.Errorf("GetBody = <nil>; want a valid function")
+	}
+}
+
+// test that each rendered request gets unique values
+func TestRequestTemplateUnique(t *testing.T) {
+
+	tmpl, err := NewRequestTemplate(http.MethodGet, "http://localhost/{{uuid}}", nil, "")
+	if err != nil {
+		t.Fatalf("NewRequestTemplate() = %v; want no error\n", err)
+	}
+
+	seen := make(map[string]bool)
+	for seq := range 100 {
+		req, err := tmpl.Request(context.Background(), TemplateData{Seq: seq})
+		if err != nil {
+			t.Fatalf("Request() = %v; want no error\n", err)
+		}
+		if seen[req.URL.Path] {
+			t.Fatalf("URL path %s rendered twice; want unique paths", req.URL.Path)
+		}
+		seen[req.URL.Path] = true
+	}
+}
+
+func TestRequestTemplateInvalid(t *testing.T) {
+
+	// a template that doesn't parse
+	if _, err := NewRequestTemplate(http.MethodGet, "http://localhost/{{.Seq", nil, ""); err == nil {
+		t.Errorf("NewRequestTemplate() error = <nil>; want a parse error")
+	}
+
+	// an invalid method
+	for _, method := range []string{"", "GE T", "GET\n"} {
+		if _, err := NewRequestTemplate(method, "http://localhost/", nil, ""); err == nil {
+			t.Errorf("NewRequestTemplate(%q) error = <nil>; want an invalid method error", method)
+		}
+	}
+
+	// a template that parses but fails to render
+	tmpl, err := NewRequestTemplate(http.MethodGet, "http://localhost/{{randInt 10 1}}", nil, "")
+	if err != nil {
+		t.Fatalf("NewRequestTemplate() = %v; want no error\n", err)
+	}
+	if _, err := tmpl.Request(context.Background(), TemplateData{}); err == nil {
+		t.Errorf("Request() error = <nil>; want a render error")
+	}
+}
+
+// test that an escaped text is rendered as is
+func TestEscapeTemplate(t *testing.T) {
+
+	for _, text := range []string{`{"query": "{{user}}"}`, "{{{{.Seq}}", "}}{{", "no action"} {
+		tmpl, err := NewRequestTemplate(http.MethodPost, "http://localhost/"+EscapeTemplate("{{id}}"), nil, EscapeTemplate(text))
+		if err != nil {
+			t.Fatalf("NewRequestTemplate(%q) = %v; want no error\n", text, err)
+		}
+		req, err := tmpl.Request(context.Background(), TemplateData{})
+		if err != nil {
+			t.Fatalf("Request() = %v; want no error\n", err)
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != text {
+			t.Errorf("body: got = %q, want = %q\n", body, text)
+		}
+		if got, want := req.URL.Path, "/{{id}}"; got != want {
+			t.Errorf("URL path: got = %q, want = %q\n", got, want)
+		}
+	}
+}
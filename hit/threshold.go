@@ -0,0 +1,137 @@
+// This file defines the thresholds of a run: pass/fail criteria checked against its [Summary]
+// e.g. "p99<500ms" or "errors<1%" (so that a run can fail a CI pipeline)
+
+package hit
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Threshold is a pass/fail criterion of a run in the form "metric op value", where
+//
+//	metric is one of:
+//	    p50, p90, p99.9, ... a percentile of the request durations (the value is a duration, e.g. 500ms)
+//	    avg, min, max        the average, fastest or slowest request duration (the value is a duration)
+//	    rps                  the throughput (the value is a number of requests per second)
+//	    errors               the percentage of failed requests (the value is a percentage, e.g. 1%)
+//	op is one of <, <=, > or >=
+type Threshold struct {
+	Metric string  // Metric is the name of the metric (e.g. "p99")
+	Op     string  // Op is the comparison operator (e.g. "<")
+	Value  float64 // Value is the limit of the metric (in nanoseconds for the durations)
+}
+
+// thresholdPattern matches a threshold (e.g. "p99 < 500ms")
+var thresholdPattern = regexp.MustCompile(`^\s*([a-z]+[0-9.]*)\s*(<=|>=|<|>)\s*(\S+)\s*$`)
+
+// ParseThreshold parses a [Threshold] (e.g. "p99<500ms" or "errors<1%").
+func ParseThreshold(s string) (Threshold, error) {
+	m := thresholdPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %q: want metric<value (e.g. p99<500ms or errors<1%%)", s)
+	}
+
+	t := Threshold{Metric: m[1], Op: m[2]}
+	if _, ok := t.percentile(); !ok && !isMetric(t.Metric) {
+		return Threshold{}, fmt.Errorf("invalid threshold %q: unknown metric %q (want pN, avg, min, max, rps or errors)", s, t.Metric)
+	}
+
+	var err error
+	switch {
+	case t.isDuration():
+		var d time.Duration
+		d, err = time.ParseDuration(m[3])
+		t.Value = float64(d)
+	case t.Metric == "errors":
+		t.Value, err = strconv.ParseFloat(strings.TrimSuffix(m[3], "%"), 64)
+	default:
+		t.Value, err = strconv.ParseFloat(m[3], 64)
+	}
+	if err != nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %q: invalid value %q", s, m[3])
+	}
+
+	return t, nil
+}
+
+// String returns the threshold in the form parsed by [ParseThreshold].
+func (t Threshold) String() string {
+	return t.Metric + t.Op + t.Format(t.Value)
+}
+
+// Check returns the value of the metric of the threshold in s
+// and reports whether it meets the threshold.
+func (t Threshold) Check(s Summary) (value float64, ok bool) {
+	switch t.Metric {
+	case "avg":
+		value = float64(s.Average)
+	case "min":
+		value = float64(s.Fastest)
+	case "max":
+		value = float64(s.Slowest)
+	case "rps":
+		value = s.RPS
+	case "errors":
+		if s.Requests > 0 {
+			value = float64(s.Errors) / float64(s.Requests) * 100
+		}
+	default:
+		p, _ := t.percentile()
+		value = float64(s.Percentile(p))
+	}
+
+	switch t.Op {
+	case "<":
+		ok = value < t.Value
+	case "<=":
+		ok = value <= t.Value
+	case ">":
+		ok = value > t.Value
+	case ">=":
+		ok = value >= t.Value
+	}
+	return value, ok
+}
+
+// Format formats a value of the metric of the threshold (e.g. "500ms" or "1%").
+func (t Threshold) Format(value float64) string {
+	switch {
+	case t.isDuration():
+		return time.Duration(value).Round(time.Microsecond).String()
+	case t.Metric == "errors":
+		return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64) + "%"
+	default:
+		return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+	}
+}
+
+// percentile returns the percentile of a pN metric.
+func (t Threshold) percentile() (float64, bool) {
+	rest, ok := strings.CutPrefix(t.Metric, "p")
+	if !ok {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(rest, 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+func (t Threshold) isDuration() bool {
+	_, ok := t.percentile()
+	return ok || t.Metric == "avg" || t.Metric == "min" || t.Metric == "max"
+}
+
+func isMetric(name string) bool {
+	switch name {
+	case "avg", "min", "max", "rps", "errors":
+		return true
+	}
+	return false
+}
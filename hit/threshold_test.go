@@ -0,0 +1,60 @@
+package hit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThreshold(t *testing.T) {
+
+	s := Summary{
+		Requests: 200,
+		Errors:   3,
+		Fastest:  time.Millisecond,
+		Slowest:  900 * time.Millisecond,
+		Average:  40 * time.Millisecond,
+		RPS:      150,
+	}
+	var h histogram
+	for range 198 {
+		h.add(20 * time.Millisecond)
+	}
+	h.add(time.Millisecond)
+	h.add(900 * time.Millisecond)
+	s.Histogram = h.snapshot()
+
+	testCases := []struct {
+		threshold string
+		want      bool
+	}{
+		{threshold: "p50<25ms", want: true},
+		{threshold: "p99.9 < 500ms", want: false},
+		{threshold: "avg<=40ms", want: true},
+		{threshold: "max<1s", want: true},
+		{threshold: "min>1ms", want: false},
+		{threshold: "rps>=100", want: true},
+		{threshold: "errors<1%", want: false},
+		{threshold: "errors<2", want: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.threshold, func(t *testing.T) {
+			th, err := ParseThreshold(tt.threshold)
+			if err != nil {
+				t.Fatalf("ParseThreshold() = %v; want no error\n", err)
+			}
+			if value, ok := th.Check(s); ok != tt.want {
+				t.Errorf("Check(): got = %v (%s), want = %v\n", ok, th.Format(value), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseThresholdInvalid(t *testing.T) {
+
+	for _, s := range []string{"", "p99", "p99<", "latency<1s", "p101<1s", "p99<fast", "errors<many", "p99=1s"} {
+		if _, err := ParseThreshold(s); err == nil {
+			t.Errorf("ParseThreshold(%q) = nil; want an error\n", s)
+		}
+	}
+}
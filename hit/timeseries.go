@@ -0,0 +1,108 @@
+// This file defines the time series of a run (e.g. the throughput and errors per second)
+
+package hit
+
+import (
+	"time"
+)
+
+// Point is an interval of a [TimeSeries] (the results that ended in it).
+type Point struct {
+	Time     time.Time     // Time is the start of the interval
+	Requests int           // Requests is the number of requests that ended in the interval
+	Errors   int           // Errors is the number of failed requests that ended in the interval
+	Bytes    int64         // Bytes is the number of bytes received in the interval
+	Average  time.Duration // Average is the average duration of the requests of the interval
+	Slowest  time.Duration // Slowest is the slowest request of the interval
+	RPS      float64       // RPS is the throughput of the interval
+}
+
+// MaxPoints bounds the span of a [TimeSeries] (e.g. a day of one second intervals)
+// so that a result with an outlier timestamp (e.g. after a clock jump) doesn't add an unbounded number of intervals.
+const MaxPoints = 86400
+
+// TimeSeries breaks the (measured) results of a run down into intervals of time, by their end time.
+// The results without timestamps are left out, and so are the results
+// that would stretch it beyond [MaxPoints] intervals (they're counted, see Dropped).
+// It is not safe for concurrent use (it is meant to be built once the results are known, e.g. from a run file).
+type TimeSeries struct {
+	interval time.Duration
+	start    time.Time // start of the first interval
+	points   []Point
+	sums     []time.Duration // sum of the request durations of each interval
+	dropped  int             // number of results outside the span of MaxPoints intervals
+}
+
+// NewTimeSeries returns a new [TimeSeries] of the given interval (e.g. one second).
+func NewTimeSeries(interval time.Duration) *TimeSeries {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &TimeSeries{interval: interval}
+}
+
+// Add adds a [Result] to the time series.
+// The warm-up results and the requests that weren't sent or completed are left out (like in a [Summary]).
+func (ts *TimeSeries) Add(r Result) {
+	if r.Skipped || r.Abandoned || r.Warmup || r.End.IsZero() || isAuthError(r.Error) {
+		return
+	}
+
+	// the intervals start at the first result (and move back if an earlier one arrives)
+	at := r.End.Truncate(ts.interval)
+	if ts.start.IsZero() {
+		ts.start = at
+	}
+	if at.Before(ts.start) {
+		n := int(ts.start.Sub(at) / ts.interval)
+		if n > MaxPoints-len(ts.points) {
+			ts.dropped++
+			return
+		}
+		ts.points = append(make([]Point, n), ts.points...)
+		ts.sums = append(make([]time.Duration, n), ts.sums...)
+		ts.start = at
+	}
+	i := int(at.Sub(ts.start) / ts.interval)
+	if i >= MaxPoints {
+		ts.dropped++
+		return
+	}
+	for len(ts.points) <= i {
+		ts.points = append(ts.points, Point{})
+		ts.sums = append(ts.sums, 0)
+	}
+
+	p := &ts.points[i]
+	p.Requests++
+	if r.Error != nil {
+		p.Errors++
+	}
+	p.Bytes += r.Bytes
+	p.Slowest = max(p.Slowest, r.Duration)
+	ts.sums[i] += r.Duration
+}
+
+// Interval returns the interval of the time series.
+func (ts *TimeSeries) Interval() time.Duration {
+	return ts.interval
+}
+
+// Dropped returns the number of results left out as they're outside the span of [MaxPoints] intervals.
+func (ts *TimeSeries) Dropped() int {
+	return ts.dropped
+}
+
+// Points returns the intervals of the time series (including the empty ones) in chronological order.
+func (ts *TimeSeries) Points() []Point {
+	points := make([]Point, len(ts.points))
+	for i, p := range ts.points {
+		p.Time = ts.start.Add(time.Duration(i) * ts.interval)
+		p.RPS = float64(p.Requests) / ts.interval.Seconds()
+		if p.Requests > 0 {
+			p.Average = ts.sums[i] / time.Duration(p.Requests)
+		}
+		points[i] = p
+	}
+	return points
+}
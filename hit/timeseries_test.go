@@ -0,0 +1,68 @@
+package hit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeSeries(t *testing.T) {
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	ts := NewTimeSeries(time.Second)
+	for _, r := range []Result{
+		{End: at(1200 * time.Millisecond), Duration: 100 * time.Millisecond, Bytes: 10},
+		{End: at(1500 * time.Millisecond), Duration: 300 * time.Millisecond, Error: errors.New("timeout")},
+		{End: at(3100 * time.Millisecond), Duration: 50 * time.Millisecond},
+		{End: at(200 * time.Millisecond), Duration: 10 * time.Millisecond},      // results may arrive out of order
+		{End: at(3200 * time.Millisecond), Duration: time.Second, Warmup: true}, // left out
+		{Skipped: true},
+	} {
+		ts.Add(r)
+	}
+
+	got := ts.Points()
+	want := []Point{
+		{Time: start, Requests: 1, Average: 10 * time.Millisecond, Slowest: 10 * time.Millisecond, RPS: 1},
+		{Time: at(time.Second), Requests: 2, Errors: 1, Bytes: 10, Average: 200 * time.Millisecond, Slowest: 300 * time.Millisecond, RPS: 2},
+		{Time: at(2 * time.Second)}, // no results
+		{Time: at(3 * time.Second), Requests: 1, Average: 50 * time.Millisecond, Slowest: 50 * time.Millisecond, RPS: 1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Points(): got %d points, want %d\n", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Requests != want[i].Requests || got[i].Errors != want[i].Errors ||
+			got[i].Bytes != want[i].Bytes || got[i].Average != want[i].Average || got[i].Slowest != want[i].Slowest || got[i].RPS != want[i].RPS {
+			t.Errorf("Points()[%d]: got = %+v, want = %+v\n", i, got[i], want[i])
+		}
+	}
+}
+
+// test that the results with outlier timestamps are dropped (and counted) instead of stretching the series
+func TestTimeSeriesOutliers(t *testing.T) {
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ts := NewTimeSeries(time.Second)
+	for _, end := range []time.Time{
+		start,
+		start.Add(time.Second),
+		start.Add(-MaxPoints * time.Second), // too early
+		start.Add(MaxPoints * time.Second),  // too late
+		start.AddDate(100, 0, 0),            // far too late
+		start.Add(-time.Second),             // still within the span
+	} {
+		ts.Add(Result{End: end, Duration: time.Millisecond})
+	}
+
+	if got := len(ts.Points()); got != 3 {
+		t.Errorf("points: got = %d, want = %d\n", got, 3)
+	}
+	if got := ts.Dropped(); got != 3 {
+		t.Errorf("Dropped(): got = %d, want = %d\n", got, 3)
+	}
+}
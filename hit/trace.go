@@ -0,0 +1,43 @@
+// This file defines the trace context propagation of the requests (see [Options])
+// each request starts a new W3C trace (https://www.w3.org/TR/trace-context/) and gets a request ID,
+// so that a slow request can be found in the server's traces and logs
+
+package hit
+
+import (
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+)
+
+// RequestIDHeader is the header of the request ID (see [Options])
+const RequestIDHeader = "X-Request-ID"
+
+// traceRequest sets a new traceparent header (sampled) and a request ID on req
+// and returns the trace ID and the request ID.
+// The request ID of the request (if any) is kept.
+func traceRequest(req *http.Request) (traceID, requestID string) {
+	var id [24]byte // trace ID (16 bytes) and parent ID (8 bytes)
+	for i := 0; i < len(id); i += 8 {
+		v := rand.Uint64() | 1 // (the IDs must not be all zeros)
+		for j := range 8 {
+			id[i+j] = byte(v >> (8 * j))
+		}
+	}
+
+	traceID = hex.EncodeToString(id[:16])
+	req.Header.Set("Traceparent", "00-"+traceID+"-"+hex.EncodeToString(id[16:])+"-01")
+
+	return traceID, setRequestID(req, RequestIDHeader)
+}
+
+// setRequestID sets a new request ID (a random UUID) in the given header of req
+// unless it already has one, and returns the request ID.
+func setRequestID(req *http.Request, header string) string {
+	id := req.Header.Get(header)
+	if id == "" {
+		id = newUUID()
+		req.Header.Set(header, id)
+	}
+	return id
+}
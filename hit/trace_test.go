@@ -0,0 +1,91 @@
+package hit
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+var traceparent = regexp.MustCompile(`^00-([0-9a-f]{32})-[0-9a-f]{16}-01$`)
+
+// test that each traced request gets a new trace and a request ID that are recorded in its result
+func TestSendNTrace(t *testing.T) {
+	const N int = 30
+
+	var (
+		mu      sync.Mutex
+		headers = make(map[string]string) // traceparent by request ID
+	)
+
+	opts := Options{Concurrency: 3, Trace: true}
+	opts.Send = func(req *http.Request) Result {
+		mu.Lock()
+		defer mu.Unlock()
+		headers[req.Header.Get(RequestIDHeader)] = req.Header.Get("Traceparent")
+
+		// the 3rd request of each 10 is slow
+		d := time.Millisecond
+		if len(headers)%10 == 3 {
+			d = time.Duration(len(headers)) * time.Second
+		}
+		return Result{Status: http.StatusOK, Duration: d}
+	}
+
+	results, err := SendN(context.Background(), N, opts, getTestHttpRequest())
+	if err != nil {
+		t.Fatalf("SendN() = %v; want no error\n", err)
+	}
+
+	sz := NewSummarizer()
+	for r := range results {
+		sz.Add(r)
+
+		mu.Lock()
+		header := headers[r.RequestID]
+		mu.Unlock()
+
+		m := traceparent.FindStringSubmatch(header)
+		if m == nil {
+			t.Fatalf("traceparent of request %q: got = %q, want a valid W3C traceparent\n", r.RequestID, header)
+		}
+		if r.TraceID != m[1] {
+			t.Errorf("TraceID: got = %q, want = %q (from the header)\n", r.TraceID, m[1])
+		}
+	}
+
+	if len(headers) != N {
+		t.Errorf("distinct request IDs: got = %d, want = %d\n", len(headers), N)
+	}
+
+	// the slowest requests are listed (slowest first) with their IDs
+	s := sz.Summary()
+	if len(s.SlowestResults) != sampleSize {
+		t.Fatalf("SlowestResults: got %d results, want %d\n", len(s.SlowestResults), sampleSize)
+	}
+	want := []time.Duration{23 * time.Second, 13 * time.Second, 3 * time.Second, time.Millisecond}
+	for i, d := range want {
+		r := s.SlowestResults[i]
+		if r.Duration != d || r.RequestID == "" {
+			t.Errorf("SlowestResults[%d]: got = %v (request ID %q), want = %v with a request ID\n", i, r.Duration, r.RequestID, d)
+		}
+	}
+}
+
+// test that a request ID set by the target is kept
+func TestTraceRequestKeepsRequestID(t *testing.T) {
+
+	req := getTestHttpRequest()
+	req.Header.Set(RequestIDHeader, "given")
+
+	traceID, requestID := traceRequest(req)
+
+	if requestID != "given" {
+		t.Errorf("request ID: got = %q, want = %q\n", requestID, "given")
+	}
+	if len(traceID) != 32 || traceID == "00000000000000000000000000000000" {
+		t.Errorf("trace ID: got = %q, want 32 hex digits (not all zeros)\n", traceID)
+	}
+}
@@ -0,0 +1,300 @@
+// Package pipeline provides typed, composable stages to build concurrent pipelines.
+//
+// A pipeline is a chain of stages connected by channels: each stage reads the values
+// of its input channel and writes its results to an output channel that it returns right away.
+// Every stage closes its output channel when its input is exhausted or its context is cancelled,
+// so that cancelling the context of a pipeline stops all of its goroutines
+// (i.e. no goroutine is left blocked on a send or a receive).
+package pipeline
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+)
+
+// Stage is a step of a pipeline that turns a channel of In values into a channel of Out values.
+// A stage must close its output channel when it returns
+// and must not block on a send once ctx is cancelled.
+type Stage[In, Out any] func(ctx context.Context, in <-chan In) <-chan Out
+
+// Pipe chains two stages (the output of the first is the input of the second).
+func Pipe[A, B, C any](first Stage[A, B], second Stage[B, C]) Stage[A, C] {
+	return func(ctx context.Context, in <-chan A) <-chan C {
+		return second(ctx, first(ctx, in))
+	}
+}
+
+// From returns a channel that delivers the values of seq (i.e. the source of a pipeline).
+// It stops pulling values from seq when ctx is cancelled.
+func From[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	return FromBuffered(ctx, 0, seq)
+}
+
+// FromBuffered is like [From] with an output buffered for size values
+// (i.e. seq is pulled up to size values ahead of the consumer,
+// which saves a goroutine handoff per value while the buffer isn't empty).
+func FromBuffered[T any](ctx context.Context, size int, seq iter.Seq[T]) <-chan T {
+	out := make(chan T, max(size, 0))
+
+	go func() {
+		defer close(out)
+		for v := range seq {
+			if !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Each returns an iterator over the values of a channel that stops when ctx is cancelled
+// (even if the channel is never closed, e.g. its sender is stuck).
+// The stages read their input with it, so that they never block on a receive once ctx is cancelled.
+func Each[T any](ctx context.Context, in <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok || !yield(v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of a channel (i.e. the sink of a pipeline).
+// Stopping the iteration early leaves the rest of the values in the channel
+// (cancel the context of the pipeline to stop it).
+func Values[T any](in <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range in {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Workers returns a stage that runs n workers (at least 1) reading from the same input.
+// Each worker is given its ID (0 to n-1) and calls emit to deliver an output.
+// emit reports false when ctx is cancelled (the worker should return then).
+// The output is closed when all the workers have returned.
+// A worker that must stop when ctx is cancelled reads its input with [Each]
+// (ranging over the input keeps reading it until it's closed, e.g. to drain it).
+func Workers[In, Out any](n int, work func(ctx context.Context, worker int, in <-chan In, emit func(Out) bool)) Stage[In, Out] {
+	return WorkersBuffered(n, 0, work)
+}
+
+// WorkersBuffered is like [Workers] with an output buffered for size values
+// (i.e. the workers run up to size outputs ahead of the consumer, see [FromBuffered]).
+func WorkersBuffered[In, Out any](n, size int, work func(ctx context.Context, worker int, in <-chan In, emit func(Out) bool)) Stage[In, Out] {
+	return func(ctx context.Context, in <-chan In) <-chan Out {
+		out := make(chan Out, max(size, 0))
+
+		emit := func(v Out) bool {
+			return send(ctx, out, v)
+		}
+
+		var wg sync.WaitGroup
+		for worker := range max(n, 1) {
+			wg.Go(func() {
+				work(ctx, worker, in, emit)
+			})
+		}
+
+		// close the output channel when all workers are done
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+
+		return out
+	}
+}
+
+// Map returns a stage that applies f to each input with n workers in parallel (i.e. bounded parallelism).
+// The outputs are delivered as they complete (i.e. not necessarily in the order of the inputs).
+func Map[In, Out any](n int, f func(context.Context, In) Out) Stage[In, Out] {
+	return Workers(n, func(ctx context.Context, _ int, in <-chan In, emit func(Out) bool) {
+		for v := range Each(ctx, in) {
+			if !emit(f(ctx, v)) {
+				return
+			}
+		}
+	})
+}
+
+// RateLimit returns a stage that delivers at most one value per interval.
+// The rate is not made up for: an input that arrives late is delivered right away
+// but the next one still waits for the next tick.
+// An interval of zero (or less) doesn't limit the rate (e.g. a rate too high for the clock resolution).
+func RateLimit[T any](interval time.Duration) Stage[T, T] {
+	return func(ctx context.Context, in <-chan T) <-chan T {
+		out := make(chan T)
+
+		go func() {
+			defer close(out)
+
+			if interval <= 0 {
+				for v := range Each(ctx, in) {
+					if !send(ctx, out, v) {
+						return
+					}
+				}
+				return
+			}
+
+			t := time.NewTicker(interval)
+			defer t.Stop()
+
+			for v := range Each(ctx, in) {
+				// wait until next tick
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					return
+				}
+
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// Batch returns a stage that groups the inputs into batches of up to size values (at least 1).
+// A partial batch is delivered once maxWait has passed since its first value
+// (or when the input is closed). A maxWait of zero waits until the batch is full.
+func Batch[T any](size int, maxWait time.Duration) Stage[T, []T] {
+	size = max(size, 1)
+
+	return func(ctx context.Context, in <-chan T) <-chan []T {
+		out := make(chan []T)
+
+		go func() {
+			defer close(out)
+
+			var (
+				batch    []T
+				deadline <-chan time.Time // nil (i.e. never) while the batch is empty
+				timer    *time.Timer
+			)
+			defer func() {
+				if timer != nil {
+					timer.Stop()
+				}
+			}()
+
+			// each batch is preallocated with the batch size
+			// (the receiver owns the delivered batches)
+			flush := func() bool {
+				b := batch
+				batch, deadline = nil, nil
+				return send(ctx, out, b)
+			}
+
+			for {
+				select {
+				case v, ok := <-in:
+					if !ok {
+						if len(batch) > 0 {
+							flush()
+						}
+						return
+					}
+
+					if len(batch) == 0 && maxWait > 0 {
+						if timer == nil {
+							timer = time.NewTimer(maxWait)
+						} else {
+							timer.Reset(maxWait)
+						}
+						deadline = timer.C
+					}
+
+					if batch == nil {
+						batch = make([]T, 0, size)
+					}
+					batch = append(batch, v)
+					if len(batch) == size && !flush() {
+						return
+					}
+				case <-deadline:
+					if !flush() {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// FanOut returns a stage that runs n copies of a stage (at least 1) reading from the same input
+// and merges their outputs (i.e. fan-out followed by fan-in).
+func FanOut[In, Out any](n int, stage Stage[In, Out]) Stage[In, Out] {
+	return func(ctx context.Context, in <-chan In) <-chan Out {
+		outs := make([]<-chan Out, max(n, 1))
+		for i := range outs {
+			outs[i] = stage(ctx, in)
+		}
+		return Merge(ctx, outs...)
+	}
+}
+
+// Merge delivers the values of all the given channels into a single channel (i.e. fan-in).
+// The output is closed when all the inputs are closed (or ctx is cancelled).
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Go(func() {
+			for v := range Each(ctx, in) {
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// Drain reads (and discards) the rest of the values of a channel until it's closed.
+// It lets the stages before it finish without cancelling their context.
+func Drain[T any](in <-chan T) {
+	for range in {
+	}
+}
+
+// send sends v to out unless ctx is cancelled first.
+// It reports whether v was sent.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	// Note that, putting the send (out <- v) on a case statement allows the runtime to choose ctx.Done()
+	// when the send is blocked and the context is cancelled.
+	// Putting it under a default: case would block if the receiver isn't ready.
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,314 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// collect reads all the values of a channel
+func collect[T any](in <-chan T) []T {
+	var got []T
+	for v := range in {
+		got = append(got, v)
+	}
+	return got
+}
+
+func TestFrom(t *testing.T) {
+
+	got := collect(From(context.Background(), slices.Values([]int{1, 2, 3})))
+
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("From(): got = %v, want = %v\n", got, want)
+	}
+}
+
+func TestValues(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []int
+	for v := range Values(From(ctx, slices.Values([]int{1, 2, 3}))) {
+		got = append(got, v)
+		if v == 2 {
+			break // stop early
+		}
+	}
+
+	if want := []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("Values(): got = %v, want = %v\n", got, want)
+	}
+}
+
+// test that Map processes all the inputs with at most n workers at a time
+func TestMap(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		const n = 3
+
+		var (
+			mu             sync.Mutex
+			inFlight, most int
+		)
+		double := func(_ context.Context, v int) int {
+			mu.Lock()
+			inFlight++
+			most = max(most, inFlight)
+			mu.Unlock()
+
+			time.Sleep(time.Second)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return 2 * v
+		}
+
+		start := time.Now()
+		ctx := context.Background()
+		got := collect(Map(n, double)(ctx, From(ctx, slices.Values([]int{1, 2, 3, 4, 5, 6}))))
+
+		slices.Sort(got) // the outputs are unordered
+		if want := []int{2, 4, 6, 8, 10, 12}; !slices.Equal(got, want) {
+			t.Errorf("Map(): got = %v, want = %v\n", got, want)
+		}
+
+		if most != n {
+			t.Errorf("parallelism: got = %d, want = %d\n", most, n)
+		}
+		if d := time.Since(start); d != 2*time.Second {
+			t.Errorf("duration: got = %v, want = %v\n", d, 2*time.Second)
+		}
+	})
+}
+
+func TestWorkers(t *testing.T) {
+
+	// each worker tags the inputs with its ID
+	tag := Workers(2, func(_ context.Context, worker int, in <-chan int, emit func([2]int) bool) {
+		for v := range in {
+			if !emit([2]int{worker, v}) {
+				return
+			}
+		}
+	})
+
+	ctx := context.Background()
+	got := collect(tag(ctx, From(ctx, slices.Values([]int{1, 2, 3, 4}))))
+
+	if len(got) != 4 {
+		t.Fatalf("Workers() returned %d outputs; want %d\n", len(got), 4)
+	}
+	for _, o := range got {
+		if o[0] < 0 || o[0] > 1 {
+			t.Errorf("worker: got = %d, want 0 or 1\n", o[0])
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+
+		var got []time.Duration
+		ctx := context.Background()
+		for range RateLimit[int](100*time.Millisecond)(ctx, From(ctx, slices.Values([]int{1, 2, 3}))) {
+			got = append(got, time.Since(start))
+		}
+
+		want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+		if !slices.Equal(got, want) {
+			t.Errorf("delivered at: got = %v, want = %v\n", got, want)
+		}
+	})
+
+	// an interval of zero doesn't limit the rate (rather than panic)
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+
+		ctx := context.Background()
+		got := collect(RateLimit[int](0)(ctx, From(ctx, slices.Values([]int{1, 2, 3}))))
+
+		if !slices.Equal(got, []int{1, 2, 3}) || time.Since(start) != 0 {
+			t.Errorf("RateLimit(0): got = %v after %v, want = %v right away\n", got, time.Since(start), []int{1, 2, 3})
+		}
+	})
+}
+
+func TestBatch(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		size    int
+		maxWait time.Duration
+		want    [][]int
+	}{
+		{name: "full_batches", size: 2, want: [][]int{{1, 2}, {3, 4}, {5}}},
+		// the inputs arrive every second (the partial batch is flushed after 1.5s)
+		{name: "max_wait", size: 3, maxWait: 1500 * time.Millisecond, want: [][]int{{1, 2}, {3, 4}, {5}}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx := context.Background()
+				in := From(ctx, slices.Values([]int{1, 2, 3, 4, 5}))
+				if tt.maxWait > 0 {
+					in = RateLimit[int](time.Second)(ctx, in)
+				}
+
+				got := collect(Batch[int](tt.size, tt.maxWait)(ctx, in))
+
+				if !slices.EqualFunc(got, tt.want, slices.Equal) {
+					t.Errorf("Batch(): got = %v, want = %v\n", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestFanOutAndMerge(t *testing.T) {
+
+	ctx := context.Background()
+
+	square := Map(1, func(_ context.Context, v int) int { return v * v })
+	got := collect(FanOut(3, square)(ctx, From(ctx, slices.Values([]int{1, 2, 3, 4}))))
+	slices.Sort(got)
+
+	if want := []int{1, 4, 9, 16}; !slices.Equal(got, want) {
+		t.Errorf("FanOut(): got = %v, want = %v\n", got, want)
+	}
+
+	merged := collect(Merge(ctx, From(ctx, slices.Values([]int{1, 2})), From(ctx, slices.Values([]int{3}))))
+	slices.Sort(merged)
+
+	if want := []int{1, 2, 3}; !slices.Equal(merged, want) {
+		t.Errorf("Merge(): got = %v, want = %v\n", merged, want)
+	}
+}
+
+func TestPipe(t *testing.T) {
+
+	ctx := context.Background()
+
+	inc := Map(1, func(_ context.Context, v int) int { return v + 1 })
+	double := Map(1, func(_ context.Context, v int) int { return 2 * v })
+
+	got := collect(Pipe(inc, double)(ctx, From(ctx, slices.Values([]int{1, 2, 3}))))
+
+	if want := []int{4, 6, 8}; !slices.Equal(got, want) {
+		t.Errorf("Pipe(): got = %v, want = %v\n", got, want)
+	}
+}
+
+// test that cancelling the context stops all the goroutines of a pipeline
+// even though the consumer stops reading (synctest fails if a goroutine is left blocked)
+// test that a buffered source and workers run ahead of the consumer by their buffer at most
+// and still stop on cancel
+func TestBuffered(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var pulled atomic.Int64
+		naturals := func(yield func(int) bool) {
+			for i := 0; ; i++ {
+				pulled.Add(1)
+				if !yield(i) {
+					return
+				}
+			}
+		}
+
+		double := WorkersBuffered(1, 4, func(ctx context.Context, _ int, in <-chan int, emit func(int) bool) {
+			for v := range Each(ctx, in) {
+				if !emit(2 * v) {
+					return
+				}
+			}
+		})
+		out := double(ctx, FromBuffered(ctx, 8, naturals))
+
+		// nothing is read: the buffers fill up
+		synctest.Wait()
+
+		// 8 in the source's buffer, 4 in the workers' buffer, 1 held by the worker and 1 by the source
+		if got, want := pulled.Load(), int64(8+4+1+1); got != want {
+			t.Errorf("values pulled: got = %d, want = %d\n", got, want)
+		}
+		if got := <-out; got != 0 {
+			t.Errorf("first output: got = %d, want = %d\n", got, 0)
+		}
+
+		cancel()
+		Drain(out)
+	})
+}
+
+func TestCancel(t *testing.T) {
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// an endless source
+		naturals := func(yield func(int) bool) {
+			for i := 0; ; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+
+		stage := Pipe(
+			Pipe(Map(4, func(_ context.Context, v int) int { return v }), RateLimit[int](time.Millisecond)),
+			FanOut(2, Batch[int](10, time.Second)),
+		)
+		out := stage(ctx, From(ctx, naturals))
+
+		<-out // read one batch and walk away
+		cancel()
+		synctest.Wait()
+
+		// the output is closed (after delivering at most the values already in flight)
+		Drain(out)
+	})
+}
+
+// test that the stages stop on cancel even if their input is never closed
+// (e.g. its sender is stuck or doesn't watch the context)
+func TestCancelOpenInput(t *testing.T) {
+
+	stages := map[string]Stage[int, int]{
+		"Map":       Map(2, func(_ context.Context, v int) int { return v }),
+		"RateLimit": RateLimit[int](time.Millisecond),
+		"FanOut":    FanOut(2, Map(1, func(_ context.Context, v int) int { return v })),
+	}
+
+	for name, stage := range stages {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			in := make(chan int) // never closed
+			out := stage(ctx, in)
+
+			cancel()
+			synctest.Wait()
+
+			select {
+			case _, ok := <-out:
+				if ok {
+					t.Errorf("%s: got a value, want the output closed\n", name)
+				}
+			default:
+				t.Errorf("%s: the output is still open after cancel\n", name)
+			}
+		})
+	}
+}